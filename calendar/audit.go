@@ -0,0 +1,93 @@
+package calendar
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/TsSol87/calendarApp/events"
+	"github.com/TsSol87/calendarApp/storage"
+)
+
+// Операции, которые возвращают события к прежним состояниям. В журнал отмены из них попадает только OpRevert.
+const (
+	OpUndo    = "undo"
+	OpRedo    = "redo"
+	OpRevert  = "revert"
+	OpRestore = "restore"
+)
+
+// AuditEntry — запись журнала аудита: кто, когда и какой операцией изменил событие и какие поля.
+type AuditEntry struct {
+	At      time.Time       `json:"at"`
+	Actor   string          `json:"actor"`
+	Op      string          `json:"op"`
+	ID      string          `json:"id"`
+	Title   string          `json:"title"`
+	Changes []events.Change `json:"changes"`
+}
+
+// EnableAudit записывает каждое изменение событий в журнал h от имени actor.
+// Журнал только дописывается, поэтому его могут вести несколько сеансов программы одновременно.
+func (c *Calendar) EnableAudit(h storage.History, actor string) {
+	c.auditLog = h
+	c.actor = actor
+}
+
+func (c *Calendar) AuditLog() storage.History {
+	return c.auditLog
+}
+
+// audit записывает изменение события id из состояния before в after; nil означает, что события не было.
+func (c *Calendar) audit(op, id string, before, after *events.Event) {
+	if c.auditLog == nil {
+		return
+	}
+	entry := AuditEntry{
+		At:      time.Now().UTC(),
+		Actor:   c.actor,
+		Op:      op,
+		ID:      id,
+		Changes: events.Diff(before, after),
+	}
+	if after != nil {
+		entry.Title = after.Title
+	} else if before != nil {
+		entry.Title = before.Title
+	}
+	record, err := json.Marshal(entry)
+	if err == nil {
+		err = c.auditLog.Append(record)
+	}
+	if err != nil {
+		log.Error("audit log saving failed", "event_id", id, "op", op, "err", err)
+	}
+}
+
+// auditState записывает изменение, когда состояние до него сохранено снимком для журнала отмены.
+func (c *Calendar) auditState(op, id string, before json.RawMessage, after *events.Event) {
+	var old *events.Event
+	if before != nil {
+		var err error
+		if old, err = decode(before); err != nil {
+			log.Error("audit state decoding failed", "event_id", id, "err", err)
+		}
+	}
+	c.audit(op, id, old, after)
+}
+
+// ScanAudit передаёт fn записи журнала аудита от старых к новым; id ограничивает их одним событием.
+func (c *Calendar) ScanAudit(id string, fn func(e AuditEntry) error) error {
+	if c.auditLog == nil {
+		return nil
+	}
+	return c.auditLog.Scan(func(record []byte) error {
+		var e AuditEntry
+		if err := json.Unmarshal(record, &e); err != nil {
+			return err
+		}
+		if id != "" && e.ID != id {
+			return nil
+		}
+		return fn(e)
+	})
+}
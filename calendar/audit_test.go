@@ -0,0 +1,59 @@
+package calendar
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/TsSol87/calendarApp/rotate"
+	"github.com/TsSol87/calendarApp/storage"
+)
+
+func TestCalendar_AuditTrail(t *testing.T) {
+	dir := t.TempDir()
+	c := newTestCalendar(t, dir)
+	audit := storage.NewHistoryFile(filepath.Join(dir, "audit.json"), rotate.Options{})
+	defer audit.Close()
+	c.EnableAudit(audit, "alice")
+
+	e, err := c.AddEvent("Meeting", "2030-01-01 10:00", "high")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := c.AddEvent("Other", "2030-01-02 10:00", "low")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.EditEvent(e.ID, "Renamed", "2030-01-01 10:00", "high"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Undo(); err != nil {
+		t.Fatal(err)
+	}
+
+	var trail []AuditEntry
+	err = c.ScanAudit(e.ID, func(entry AuditEntry) error {
+		trail = append(trail, entry)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trail) != 3 || trail[0].Op != OpAdd || trail[1].Op != OpUpdate || trail[2].Op != OpUndo {
+		t.Fatalf("Expected add, update and undo of the event, got %+v", trail)
+	}
+	for _, entry := range trail {
+		if entry.Actor != "alice" || entry.ID != e.ID || entry.At.IsZero() {
+			t.Errorf("Unexpected audit entry: %+v", entry)
+		}
+		if entry.ID == other.ID {
+			t.Errorf("Expected entries of another event to be filtered out")
+		}
+	}
+	update := trail[1].Changes
+	if len(update) != 1 || update[0].Field != "title" || update[0].Before != "Meeting" || update[0].After != "Renamed" {
+		t.Errorf("Expected only the title change, got %+v", update)
+	}
+	if len(trail[0].Changes) == 0 || trail[0].Changes[0].Before != "" {
+		t.Errorf("Expected add to change fields from empty values, got %+v", trail[0].Changes)
+	}
+}
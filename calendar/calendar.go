@@ -1,52 +1,108 @@
 package calendar
 
 import (
-	"encoding/json"
 	"github.com/TsSol87/calendarApp/logger"
 	"github.com/TsSol87/calendarApp/storage"
 	"time"
 
 	//"github.com/TsSol87/calendarApp/storage"
 
-	//"errors"
 	"fmt"
 	"github.com/TsSol87/calendarApp/events"
 	//"time"
 )
 
+var log = logger.Component("calendar")
+
 type Calendar struct {
 	calendarEvents map[string]*events.Event
-	storage        storage.Store
+	repository     storage.Repository
 	Notification   chan string
+	undo           undoLog
+	undoStore      storage.Store
+	undoDepth      int
+	auditLog       storage.History
+	actor          string
 }
 
+// Save записывает все события одной транзакцией, например чтобы сохранить отметки об отправленных напоминаниях.
 func (c *Calendar) Save() error {
-	data, err := json.Marshal(c.calendarEvents)
-	if err != nil {
+	c.sync()
+	return c.repository.Transaction(func(tx storage.Repository) error {
+		for _, e := range c.calendarEvents {
+			if err := tx.Put(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
 
-		return err
-	}
-	err = c.storage.Save(data)
+func (c *Calendar) Load() error {
+	list, err := c.repository.All()
 	if err != nil {
 		return err
 	}
+	c.calendarEvents = list
+	for _, e := range c.calendarEvents {
+		e.ResumeReminder(c.Notify)
+	}
 	return nil
 }
 
-func (c *Calendar) Load() error {
-	data, err := c.storage.Load()
+// Restore заменяет все события календаря набором list (например, из снимка) одной транзакцией.
+func (c *Calendar) Restore(list map[string]*events.Event) error {
+	c.sync()
+	err := c.repository.Transaction(func(tx storage.Repository) error {
+		current, err := tx.All()
+		if err != nil {
+			return err
+		}
+		for id := range current {
+			if _, ok := list[id]; !ok {
+				if err := tx.Delete(id); err != nil {
+					return err
+				}
+			}
+		}
+		for _, e := range list {
+			if err := tx.Put(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return err
+		return c.conflict(fmt.Errorf("error restoring the calendar: %w", err))
 	}
-	err = json.Unmarshal(data, &c.calendarEvents)
-	if err != nil {
-		return err
+	old := c.calendarEvents
+	for id := range old {
+		c.stopReminder(id)
+	}
+	c.calendarEvents = make(map[string]*events.Event, len(list))
+	for id, e := range list {
+		c.calendarEvents[id] = e
+		e.ResumeReminder(c.Notify)
+	}
+	for id, e := range old {
+		if _, ok := list[id]; !ok {
+			c.audit(OpRestore, id, e, nil)
+		}
+	}
+	for id, e := range list {
+		if len(events.Diff(old[id], e)) > 0 {
+			c.audit(OpRestore, id, old[id], e)
+		}
 	}
 	return nil
 }
 
-func NewCalendar(s storage.Store) *Calendar {
-	return &Calendar{calendarEvents: make(map[string]*events.Event), storage: s, Notification: make(chan string)}
+func (c *Calendar) Repository() storage.Repository {
+	return c.repository
+}
+
+func NewCalendar(r storage.Repository) *Calendar {
+	return &Calendar{calendarEvents: make(map[string]*events.Event), repository: r, Notification: make(chan string)}
 }
 
 func (c *Calendar) AddEvent(title string, dateStr string, priorityStr string) (*events.Event, error) {
@@ -55,14 +111,17 @@ func (c *Calendar) AddEvent(title string, dateStr string, priorityStr string) (*
 		return nil, err
 	}
 
-	c.calendarEvents[e.ID] = e
-	errSave := c.Save()
+	c.sync()
+	errSave := c.repository.Put(e)
 	if errSave != nil {
-		return nil, errSave
+		return nil, c.conflict(errSave)
 	}
+	c.calendarEvents[e.ID] = e
+	c.record(OpAdd, e.ID, nil, e)
 	return e, nil
 }
 func (c *Calendar) GetEvents() map[string]*events.Event {
+	c.sync()
 	eventsCopy := make(map[string]*events.Event)
 	for key, value := range c.calendarEvents {
 		eventsCopy[key] = value
@@ -70,43 +129,88 @@ func (c *Calendar) GetEvents() map[string]*events.Event {
 	return eventsCopy
 }
 
-func (c *Calendar) DeleteEvent(id string) error {
+// EventsBetween возвращает события с началом в [from, to), выбранные хранилищем по времени.
+func (c *Calendar) EventsBetween(from, to time.Time) ([]*events.Event, error) {
+	c.sync()
+	found, err := c.repository.Range(from, to)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*events.Event, 0, len(found))
+	for _, e := range found {
+		if cached, ok := c.calendarEvents[e.ID]; ok {
+			e = cached
+		}
+		list = append(list, e)
+	}
+	return list, nil
+}
 
-	_, exists := c.calendarEvents[id]
+func (c *Calendar) DeleteEvent(id string) error {
+	c.sync()
+	e, exists := c.calendarEvents[id]
 	if !exists {
 		return fmt.Errorf("event with key %q not found", id)
 	}
+	before := snapshot(e)
 
-	delete(c.calendarEvents, id)
-
-	errSave := c.Save()
+	errSave := c.repository.Delete(id)
 	if errSave != nil {
-		return fmt.Errorf("error saving after deletion: %w", errSave)
+		return c.conflict(fmt.Errorf("error saving after deletion: %w", errSave))
 	}
+	c.stopReminder(id)
+	delete(c.calendarEvents, id)
+	c.record(OpRemove, id, before, nil)
 	return nil
 
 }
 
 func (c *Calendar) EditEvent(id, title string, date string, priorityStr string) error {
-
+	c.sync()
 	e, exists := c.calendarEvents[id]
 	if !exists {
 		return fmt.Errorf("event with key %q not found", id)
 	}
 
+	before := snapshot(e)
 	err := e.Update(title, date, priorityStr)
 	if err != nil {
 		return err
 	}
-	errSave := c.Save()
+	errSave := c.repository.Put(e)
 	if errSave != nil {
-		return fmt.Errorf("error saving after event change: %w", errSave)
+		return c.conflict(fmt.Errorf("error saving after event change: %w", errSave))
 	}
+	c.record(OpUpdate, id, before, e)
 	return nil
 }
 
-func (c *Calendar) SetEventReminder(id, message string, dateStr string) error {
+// PatchEvent меняет только переданные поля события id и возвращает, что именно изменилось.
+// Если ничего не изменилось, событие не сохраняется и в журнал отмены не попадает.
+func (c *Calendar) PatchEvent(id string, p events.Patch) ([]events.Change, error) {
+	c.sync()
+	e, exists := c.calendarEvents[id]
+	if !exists {
+		return nil, fmt.Errorf("event with key %q not found", id)
+	}
+
+	before := snapshot(e)
+	changed := *e
+	changes, err := changed.Patch(p)
+	if err != nil || len(changes) == 0 {
+		return nil, err
+	}
+	errSave := c.repository.Put(&changed)
+	if errSave != nil {
+		return nil, c.conflict(fmt.Errorf("error saving after event change: %w", errSave))
+	}
+	*e = changed
+	c.record(OpUpdate, id, before, e)
+	return changes, nil
+}
 
+func (c *Calendar) SetEventReminder(id, message string, dateStr string) error {
+	c.sync()
 	e, exists := c.calendarEvents[id]
 	if !exists {
 		return fmt.Errorf("event with key %q not found", id)
@@ -114,7 +218,7 @@ func (c *Calendar) SetEventReminder(id, message string, dateStr string) error {
 
 	at, errDateStr := events.TimeParse(dateStr)
 	if errDateStr != nil {
-		return fmt.Errorf("can't create date: %w", events.ErrIsValidDate)
+		return fmt.Errorf("can't create date: %w: %w", events.ErrIsValidDate, errDateStr)
 	}
 
 	now := time.Now().In(at.Location())
@@ -122,31 +226,92 @@ func (c *Calendar) SetEventReminder(id, message string, dateStr string) error {
 		return fmt.Errorf("no reminder has been added: time %q has already passed", at)
 	}
 
+	before := snapshot(e)
+	c.stopReminder(id)
 	err := e.AddReminder(message, at, c.Notify)
 	if err != nil {
 		return err
 	}
-	errSave := c.Save()
+	errSave := c.repository.Put(e)
 	if errSave != nil {
-		return fmt.Errorf("error saving the calendar: %w", errSave)
+		return c.conflict(fmt.Errorf("error saving the calendar: %w", errSave))
 	}
+	c.record(OpSetReminder, id, before, e)
 
 	return nil
 }
 
 func (c *Calendar) CancelEventReminder(id string) error {
-
+	c.sync()
 	e, exists := c.calendarEvents[id]
 	if !exists {
 		return fmt.Errorf("event with key %q not found", id)
 	}
+	before := snapshot(e)
 	e.RemoveReminder()
-	errSave := c.Save()
+	errSave := c.repository.Put(e)
+	if errSave != nil {
+		log.Error("saving the calendar failed", "event_id", e.ID, "err", errSave)
+		return c.conflict(fmt.Errorf("error saving the calendar: %w", errSave))
+	}
+	c.record(OpCancelReminder, id, before, e)
+	return nil
+}
+
+// TagEvent добавляет событию id метки list и возвращает те, которых у него ещё не было.
+func (c *Calendar) TagEvent(id string, list []string) ([]string, error) {
+	return c.changeTags(OpTag, id, list, (*events.Event).AddTags)
+}
+
+// UntagEvent снимает с события id метки list и возвращает те, что у него были.
+func (c *Calendar) UntagEvent(id string, list []string) ([]string, error) {
+	return c.changeTags(OpUntag, id, list, (*events.Event).RemoveTags)
+}
+
+func (c *Calendar) changeTags(op, id string, list []string, change func(e *events.Event, list ...string) ([]string, error)) ([]string, error) {
+	c.sync()
+	e, exists := c.calendarEvents[id]
+	if !exists {
+		return nil, fmt.Errorf("event with key %q not found", id)
+	}
+	before := snapshot(e)
+	changed, err := change(e, list...)
+	if err != nil || len(changed) == 0 {
+		return nil, err
+	}
+	errSave := c.repository.Put(e)
 	if errSave != nil {
-		logMessage := fmt.Sprintf("error saving the calendar: (id: %s): %v", e.ID, errSave)
-		logger.Error(logMessage)
-		return fmt.Errorf("error saving the calendar: %w", errSave)
+		return nil, c.conflict(fmt.Errorf("error saving the calendar: %w", errSave))
+	}
+	c.record(op, id, before, e)
+	return changed, nil
+}
+
+// Tags возвращает метки событий календаря и число событий с каждой из них.
+func (c *Calendar) Tags() map[string]int {
+	c.sync()
+	counts := make(map[string]int)
+	for _, e := range c.calendarEvents {
+		for _, tag := range e.Tags {
+			counts[tag]++
+		}
+	}
+	return counts
+}
+
+// Revert возвращает событие id к сохранённому состоянию state, например к старой версии из истории хранилища;
+// nil удаляет событие. Изменение можно отменить командой undo.
+func (c *Calendar) Revert(id string, state *events.Event) error {
+	c.sync()
+	current := c.calendarEvents[id]
+	if current == nil && state == nil {
+		return fmt.Errorf("event with key %q not found", id)
+	}
+	before := snapshot(current)
+	if err := c.restore(id, snapshot(state)); err != nil {
+		return c.conflict(fmt.Errorf("error reverting event: %w", err))
 	}
+	c.record(OpRevert, id, before, c.calendarEvents[id])
 	return nil
 }
 
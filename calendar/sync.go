@@ -0,0 +1,68 @@
+package calendar
+
+import (
+	"errors"
+
+	"github.com/TsSol87/calendarApp/events"
+	"github.com/TsSol87/calendarApp/storage"
+)
+
+// Sync перечитывает события, если данные изменил другой сеанс программы.
+// Таймеры заменённых событий останавливаются, а у новых запускаются заново.
+func (c *Calendar) Sync() error {
+	r, ok := c.repository.(storage.Refresher)
+	if !ok {
+		return nil
+	}
+	changed, err := r.Refresh()
+	if err != nil || !changed {
+		return err
+	}
+	list, err := c.repository.All()
+	if err != nil {
+		return err
+	}
+
+	for id, old := range c.calendarEvents {
+		if e, ok := list[id]; !ok || e != old {
+			c.stopReminder(id)
+		}
+	}
+	for id, e := range list {
+		old, ok := c.calendarEvents[id]
+		if ok && old == e {
+			continue
+		}
+		if ok {
+			keepSent(old, e)
+		}
+		e.ResumeReminder(c.Notify)
+	}
+	c.calendarEvents = list
+	return nil
+}
+
+// sync вызывается перед каждой операцией; ошибка только записывается в журнал, чтобы не мешать работе с тем, что уже загружено.
+func (c *Calendar) sync() {
+	if err := c.Sync(); err != nil {
+		log.Error("calendar reload failed", "err", err)
+	}
+}
+
+// conflict перечитывает календарь, если запись отклонена из-за изменений в другом сеансе.
+func (c *Calendar) conflict(err error) error {
+	if errors.Is(err, storage.ErrConflict) {
+		c.sync()
+	}
+	return err
+}
+
+// keepSent переносит отметку об отправке, если напоминание то же самое, а отметка ещё не записана.
+func keepSent(old, e *events.Event) {
+	if old.Reminder == nil || e.Reminder == nil || !old.Reminder.Sent {
+		return
+	}
+	if old.Reminder.Message == e.Reminder.Message && old.Reminder.At.Equal(e.Reminder.At) {
+		e.Reminder.Sent = true
+	}
+}
@@ -0,0 +1,46 @@
+package calendar
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/TsSol87/calendarApp/storage"
+)
+
+func TestCalendar_SyncBetweenSessions(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data.json")
+	open := func() *Calendar {
+		c := NewCalendar(storage.NewRepository(storage.NewJsonStorage(filename)))
+		if err := c.Load(); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	first, second := open(), open()
+
+	e, err := first.AddEvent("Meeting", "2030-01-01 10:00", "high")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := second.GetEvents()[e.ID]; !ok {
+		t.Fatal("Expected the second session to see the event added in the first one")
+	}
+
+	if err := first.EditEvent(e.ID, "From first", "2030-01-01 10:00", "high"); err != nil {
+		t.Fatal(err)
+	}
+	// Второй сеанс ещё не видел правку и меняет то же событие.
+	stale := *second.calendarEvents[e.ID]
+	stale.Title = "From second"
+	err = second.repository.Put(&stale)
+	if !errors.Is(err, storage.ErrConflict) {
+		t.Fatalf("Expected ErrConflict, got %v", err)
+	}
+	if err := second.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := second.GetEvents()[e.ID].Title; got != "From first" {
+		t.Errorf("Expected the conflicting session to reload %q, got %q", "From first", got)
+	}
+}
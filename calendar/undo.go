@@ -0,0 +1,203 @@
+package calendar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/TsSol87/calendarApp/events"
+	"github.com/TsSol87/calendarApp/storage"
+)
+
+var ErrNothingToUndo = errors.New("nothing to undo")
+var ErrNothingToRedo = errors.New("nothing to redo")
+
+const (
+	OpAdd            = "add"
+	OpUpdate         = "update"
+	OpRemove         = "remove"
+	OpSetReminder    = "reminder"
+	OpCancelReminder = "cancel-reminder"
+	OpTag            = "tag"
+	OpUntag          = "untag"
+)
+
+// Operation описывает изменение одного события: состояние до и после него.
+// Отмена возвращает Before, повтор — After; nil означает, что события не было.
+type Operation struct {
+	Op     string          `json:"op"`
+	ID     string          `json:"id"`
+	Title  string          `json:"title"`
+	At     time.Time       `json:"at"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+type undoLog struct {
+	Undo []Operation `json:"undo"`
+	Redo []Operation `json:"redo"`
+}
+
+// EnableUndo включает журнал отмены, сохраняемый в s между запусками; depth ограничивает число шагов.
+func (c *Calendar) EnableUndo(s storage.Store, depth int) error {
+	c.undoStore = s
+	c.undoDepth = depth
+	data, err := s.Load()
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &c.undo); err != nil {
+		return fmt.Errorf("can't read undo log %s: %w", s.GetFilename(), err)
+	}
+	c.undo.Undo = trim(c.undo.Undo, depth)
+	c.undo.Redo = trim(c.undo.Redo, depth)
+	return nil
+}
+
+func (c *Calendar) UndoStore() storage.Store {
+	return c.undoStore
+}
+
+// UndoHistory возвращает операции, которые можно отменить, начиная с последней.
+func (c *Calendar) UndoHistory() []Operation {
+	list := make([]Operation, 0, len(c.undo.Undo))
+	for i := len(c.undo.Undo) - 1; i >= 0; i-- {
+		list = append(list, c.undo.Undo[i])
+	}
+	return list
+}
+
+func (c *Calendar) Undo() (*Operation, error) {
+	c.sync()
+	if len(c.undo.Undo) == 0 {
+		return nil, ErrNothingToUndo
+	}
+	op := c.undo.Undo[len(c.undo.Undo)-1]
+	before := snapshot(c.calendarEvents[op.ID])
+	if err := c.restore(op.ID, op.Before); err != nil {
+		return nil, c.conflict(fmt.Errorf("can't undo %s of %q: %w", op.Op, op.ID, err))
+	}
+	c.auditState(OpUndo, op.ID, before, c.calendarEvents[op.ID])
+	c.undo.Undo = c.undo.Undo[:len(c.undo.Undo)-1]
+	c.undo.Redo = trim(append(c.undo.Redo, op), c.undoDepth)
+	return &op, c.saveUndo()
+}
+
+func (c *Calendar) Redo() (*Operation, error) {
+	c.sync()
+	if len(c.undo.Redo) == 0 {
+		return nil, ErrNothingToRedo
+	}
+	op := c.undo.Redo[len(c.undo.Redo)-1]
+	before := snapshot(c.calendarEvents[op.ID])
+	if err := c.restore(op.ID, op.After); err != nil {
+		return nil, c.conflict(fmt.Errorf("can't redo %s of %q: %w", op.Op, op.ID, err))
+	}
+	c.auditState(OpRedo, op.ID, before, c.calendarEvents[op.ID])
+	c.undo.Redo = c.undo.Redo[:len(c.undo.Redo)-1]
+	c.undo.Undo = trim(append(c.undo.Undo, op), c.undoDepth)
+	return &op, c.saveUndo()
+}
+
+// snapshot сохраняет состояние события до изменения, чтобы затем записать его в журнал отмены.
+func snapshot(e *events.Event) json.RawMessage {
+	if e == nil {
+		return nil
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// record добавляет выполненную операцию в журнал отмены; новая операция сбрасывает возможность повтора.
+func (c *Calendar) record(op, id string, before json.RawMessage, after *events.Event) {
+	log.Info("event changed", "op", op, "event_id", id)
+	c.auditState(op, id, before, after)
+	if c.undoDepth <= 0 {
+		return
+	}
+	title := ""
+	if after != nil {
+		title = after.Title
+	} else if e, err := decode(before); err == nil {
+		title = e.Title
+	}
+	c.undo.Undo = trim(append(c.undo.Undo, Operation{
+		Op:     op,
+		ID:     id,
+		Title:  title,
+		At:     time.Now().UTC(),
+		Before: before,
+		After:  snapshot(after),
+	}), c.undoDepth)
+	c.undo.Redo = nil
+	if err := c.saveUndo(); err != nil {
+		log.Error("undo log saving failed", "event_id", id, "err", err)
+	}
+}
+
+// restore приводит событие id к сохранённому состоянию: удаляет его или записывает заново с таймером напоминания.
+func (c *Calendar) restore(id string, state json.RawMessage) error {
+	if state == nil {
+		if err := c.repository.Delete(id); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+		c.stopReminder(id)
+		delete(c.calendarEvents, id)
+		return nil
+	}
+
+	e, err := decode(state)
+	if err != nil {
+		return err
+	}
+	if err := c.repository.Put(e); err != nil {
+		return err
+	}
+	c.stopReminder(id)
+	c.calendarEvents[id] = e
+	e.ResumeReminder(c.Notify)
+	return nil
+}
+
+func (c *Calendar) stopReminder(id string) {
+	if e, ok := c.calendarEvents[id]; ok && e.Reminder != nil && e.Reminder.Timer != nil {
+		e.Reminder.Timer.Stop()
+	}
+}
+
+func (c *Calendar) saveUndo() error {
+	if c.undoStore == nil {
+		return nil
+	}
+	data, err := json.Marshal(c.undo)
+	if err != nil {
+		return err
+	}
+	return c.undoStore.Save(data)
+}
+
+func decode(state json.RawMessage) (*events.Event, error) {
+	var e events.Event
+	if err := json.Unmarshal(state, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func trim(list []Operation, depth int) []Operation {
+	if depth <= 0 {
+		return nil
+	}
+	if len(list) > depth {
+		list = append([]Operation(nil), list[len(list)-depth:]...)
+	}
+	return list
+}
@@ -0,0 +1,151 @@
+package calendar
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/TsSol87/calendarApp/events"
+	"github.com/TsSol87/calendarApp/storage"
+)
+
+func newTestCalendar(t *testing.T, dir string) *Calendar {
+	t.Helper()
+	c := NewCalendar(storage.NewRepository(storage.NewJsonStorage(filepath.Join(dir, "data.json"))))
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.EnableUndo(storage.NewJsonStorage(filepath.Join(dir, "undo.json")), 10); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCalendar_UndoRedo(t *testing.T) {
+	dir := t.TempDir()
+	c := newTestCalendar(t, dir)
+	e, err := c.AddEvent("Meeting", "2030-01-01 10:00", "high")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.EditEvent(e.ID, "Renamed", "2030-01-01 11:00", "low"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DeleteEvent(e.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Журнал отмены переживает перезапуск.
+	c = newTestCalendar(t, dir)
+	if op, err := c.Undo(); err != nil || op.Op != OpRemove {
+		t.Fatalf("Expected to undo remove, got %+v (%v)", op, err)
+	}
+	if got := c.GetEvents()[e.ID]; got == nil || got.Title != "Renamed" {
+		t.Fatalf("Expected removed event to be restored, got %+v", got)
+	}
+	if _, err := c.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.GetEvents()[e.ID]; got.Title != "Meeting" || got.Priority != "high" {
+		t.Errorf("Expected original fields after undoing update, got %+v", got)
+	}
+	if _, err := c.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.GetEvents()) != 0 {
+		t.Errorf("Expected no events after undoing add, got %d", len(c.GetEvents()))
+	}
+	if _, err := c.Undo(); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("Expected ErrNothingToUndo, got %v", err)
+	}
+
+	if op, err := c.Redo(); err != nil || op.Op != OpAdd {
+		t.Fatalf("Expected to redo add, got %+v (%v)", op, err)
+	}
+	c = newTestCalendar(t, dir)
+	if got := c.GetEvents()[e.ID]; got == nil || got.Title != "Meeting" {
+		t.Errorf("Expected redone event to be stored, got %+v", got)
+	}
+
+	if _, err := c.AddEvent("Another", "2030-01-02 10:00", "low"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Redo(); !errors.Is(err, ErrNothingToRedo) {
+		t.Errorf("Expected new operation to clear redo, got %v", err)
+	}
+}
+
+func TestCalendar_UndoDepth(t *testing.T) {
+	c := NewCalendar(storage.NewRepository(storage.NewJsonStorage(filepath.Join(t.TempDir(), "data.json"))))
+	c.EnableUndo(storage.NewJsonStorage(filepath.Join(t.TempDir(), "undo.json")), 2)
+	for _, title := range []string{"First", "Second", "Third"} {
+		if _, err := c.AddEvent(title, "2030-01-01 10:00", "low"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(c.UndoHistory()); n != 2 {
+		t.Errorf("Expected undo history limited to 2, got %d", n)
+	}
+}
+
+func TestCalendar_TagsPersistAndUndo(t *testing.T) {
+	dir := t.TempDir()
+	c := newTestCalendar(t, dir)
+	e, err := c.AddEvent("Meeting", "2030-01-01 10:00", "high")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added, err := c.TagEvent(e.ID, []string{"work", "travel"}); err != nil || len(added) != 2 {
+		t.Fatalf("Expected two tags to be added, got %v (%v)", added, err)
+	}
+	if added, err := c.TagEvent(e.ID, []string{"work"}); err != nil || len(added) != 0 {
+		t.Errorf("Expected no change for an existing tag, got %v (%v)", added, err)
+	}
+	if removed, err := c.UntagEvent(e.ID, []string{"travel"}); err != nil || len(removed) != 1 {
+		t.Fatalf("Expected travel to be removed, got %v (%v)", removed, err)
+	}
+
+	c = newTestCalendar(t, dir)
+	if tags := c.Tags(); len(tags) != 1 || tags["work"] != 1 {
+		t.Errorf("Expected the work tag after reload, got %v", tags)
+	}
+	if op, err := c.Undo(); err != nil || op.Op != OpUntag {
+		t.Fatalf("Expected to undo untag, got %+v (%v)", op, err)
+	}
+	if got := c.GetEvents()[e.ID].Tags; len(got) != 2 {
+		t.Errorf("Expected both tags after undo, got %v", got)
+	}
+}
+
+func TestCalendar_PatchEvent(t *testing.T) {
+	dir := t.TempDir()
+	c := newTestCalendar(t, dir)
+	e, err := c.AddEvent("Meeting", "2030-01-01 10:00", "high")
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := c.PatchEvent(e.ID, events.Patch{events.FieldLocation: "Room 4", events.FieldPriority: "high"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Field != "location" {
+		t.Errorf("Expected only the location change, got %+v", changes)
+	}
+	if _, err := c.PatchEvent(e.ID, events.Patch{events.FieldURL: "not a link"}); !errors.Is(err, events.ErrIsValidField) {
+		t.Errorf("Expected ErrIsValidField, got %v", err)
+	}
+	if changes, err := c.PatchEvent(e.ID, events.Patch{events.FieldLocation: "Room 4"}); err != nil || len(changes) != 0 {
+		t.Errorf("Expected no changes for the same value, got %+v (%v)", changes, err)
+	}
+
+	c = newTestCalendar(t, dir)
+	if got := c.GetEvents()[e.ID]; got.Place != "Room 4" || got.Title != "Meeting" {
+		t.Errorf("Expected only the location to change, got %+v", got)
+	}
+	if op, err := c.Undo(); err != nil || op.Op != OpUpdate {
+		t.Fatalf("Expected to undo the field change, got %+v (%v)", op, err)
+	}
+	if got := c.GetEvents()[e.ID]; got.Place != "" {
+		t.Errorf("Expected no location after undo, got %q", got.Place)
+	}
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/TsSol87/calendarApp/calendar"
+	"github.com/TsSol87/calendarApp/events"
+)
+
+const auditUsage = "Формат: audit [\"ID события\"] [--json] [--output \"файл\"]"
+
+// showAudit печатает, кто и как менял событие (или все события), с изменёнными полями;
+// с --json или --output выгружает записи в JSON.
+func (c *Cmd) showAudit(args []string) {
+	fs := newFlagSet("audit")
+	asJSON := fs.Bool("json", false, "вывести записи в JSON")
+	output := fs.String("output", "", "записать JSON в файл")
+	positional, err := parseArgs(fs, args)
+	if err != nil || len(positional) > 1 {
+		fmt.Println(auditUsage)
+		return
+	}
+	if c.calendar.AuditLog() == nil {
+		fmt.Println("Журнал аудита не ведётся")
+		return
+	}
+	id := ""
+	if len(positional) == 1 {
+		id = positional[0]
+	}
+
+	if *asJSON || *output != "" {
+		if err := c.exportAudit(id, *output); err != nil {
+			log.Error("audit export failed", "event_id", id, "file", *output, "err", err)
+			fmt.Println("Ошибка выгрузки журнала аудита:", err)
+		}
+		return
+	}
+
+	found := 0
+	loc := events.ViewerLocation()
+	err = c.calendar.ScanAudit(id, func(e calendar.AuditEntry) error {
+		found++
+		fmt.Printf("%s  %s  %s \"%s\" (%s)\n", e.At.In(loc).Format("2006-01-02 15:04:05"), e.Actor, operationNames[e.Op], e.Title, e.ID)
+		for _, ch := range e.Changes {
+			fmt.Printf("     %s: \"%s\" -> \"%s\"\n", ch.Field, ch.Before, ch.After)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error("audit reading failed", "event_id", id, "err", err)
+		fmt.Println("Ошибка чтения журнала аудита:", err)
+		return
+	}
+	if found == 0 && id != "" {
+		fmt.Printf("Для события '%s' нет записей аудита\n", id)
+	} else if found == 0 {
+		fmt.Println("Журнал аудита пуст")
+	}
+}
+
+// exportAudit пишет записи JSON-массивом в файл или на экран, не собирая их в памяти.
+func (c *Cmd) exportAudit(id, filename string) (err error) {
+	var w io.Writer = os.Stdout
+	if filename != "" {
+		f, err := os.Create(filename)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if errClose := f.Close(); err == nil {
+				err = errClose
+			}
+		}()
+		w = f
+	}
+
+	count := 0
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	err = c.calendar.ScanAudit(id, func(e calendar.AuditEntry) error {
+		data, err := json.MarshalIndent(e, "  ", "  ")
+		if err != nil {
+			return err
+		}
+		sep := "\n  "
+		if count > 0 {
+			sep = ",\n  "
+		}
+		count++
+		_, err = fmt.Fprint(w, sep, string(data))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		_, err = io.WriteString(w, "\n]\n")
+	} else {
+		_, err = io.WriteString(w, "]\n")
+	}
+	if err == nil && filename != "" {
+		fmt.Printf("Записей аудита: %d, сохранено в %s\n", count, filename)
+	}
+	return err
+}
@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/TsSol87/calendarApp/config"
+	"github.com/TsSol87/calendarApp/events"
+	"github.com/TsSol87/calendarApp/storage"
+)
+
+// openSnapshots возвращает каталог снимков; если данные зашифрованы, снимки шифруются тем же ключом.
+func openSnapshots(c *Cmd) (*storage.SnapshotStore, error) {
+	dir, err := c.config.BackupPath()
+	if err != nil {
+		return nil, err
+	}
+	var data *storage.EncryptedStorage
+	if blob, ok := c.calendar.Repository().(*storage.BlobRepository); ok {
+		data, _ = blob.Store().(*storage.EncryptedStorage)
+	}
+	return snapshotStore(dir, data), nil
+}
+
+func snapshotStore(dir string, data *storage.EncryptedStorage) *storage.SnapshotStore {
+	snapshots := storage.NewSnapshotStore(dir)
+	if data != nil {
+		snapshots.WithOpener(func(filename string) storage.Store {
+			return data.Wrap(storage.NewJsonStorage(filename))
+		})
+	}
+	return snapshots
+}
+
+// EncryptSnapshots шифрует ключом данных снимки, сделанные до включения шифрования.
+func EncryptSnapshots(cfg *config.Config, data *storage.EncryptedStorage) error {
+	dir, err := cfg.BackupPath()
+	if err != nil {
+		return err
+	}
+	return snapshotStore(dir, data).Encrypt()
+}
+
+// autoBackup делает снимок после команды: в режиме change — если календарь изменился,
+// в режиме schedule — если с последнего снимка прошло backup_interval. Снимки по расписанию
+// проверяются между командами: пока программа ждёт ввода, данные в этом окне не меняются.
+func (c *Cmd) autoBackup() {
+	if c.snapshots == nil || c.config.Backup == config.BackupOff {
+		return
+	}
+	now := time.Now()
+	if c.config.Backup == config.BackupSchedule {
+		if c.lastSnapshot.IsZero() {
+			latest, err := c.snapshots.Latest()
+			if err != nil {
+				log.Error("snapshot listing failed", "dir", c.snapshots.Dir(), "err", err)
+				return
+			}
+			c.lastSnapshot = latest
+		}
+		if now.Sub(c.lastSnapshot) < c.config.BackupEvery() {
+			return
+		}
+	}
+	if _, err := c.snapshot(now); err != nil {
+		log.Error("snapshot failed", "dir", c.snapshots.Dir(), "err", err)
+	}
+}
+
+// snapshot сохраняет текущее состояние календаря и удаляет лишние снимки по правилам хранения.
+func (c *Cmd) snapshot(now time.Time) (*storage.Snapshot, error) {
+	snapshot, created, err := c.snapshots.Create(c.calendar.GetEvents(), now)
+	if err != nil {
+		return nil, err
+	}
+	c.lastSnapshot = now
+	if !created {
+		return nil, nil
+	}
+	log.Info("snapshot created", "snapshot", snapshot.ID)
+	removed, err := c.snapshots.Prune(c.config.Retention())
+	for _, s := range removed {
+		log.Info("snapshot removed by retention policy", "snapshot", s.ID)
+	}
+	return snapshot, err
+}
+
+func (c *Cmd) backupCommand(args []string) {
+	if c.snapshots == nil {
+		fmt.Println("Каталог снимков недоступен")
+		return
+	}
+	if len(args) == 0 {
+		args = []string{"now"}
+	}
+	switch args[0] {
+	case "now":
+		snapshot, err := c.snapshot(time.Now())
+		if err != nil {
+			log.Error("snapshot failed", "dir", c.snapshots.Dir(), "err", err)
+			fmt.Println("Ошибка:", err)
+			return
+		}
+		if snapshot == nil {
+			fmt.Println("Календарь не изменился с последнего снимка")
+			return
+		}
+		fmt.Printf("Снимок %s создан (событий: %d)\n", snapshot.ID, snapshot.Events)
+	case "list":
+		c.listSnapshots()
+	case "diff":
+		if len(args) < 2 {
+			fmt.Println("Формат: backup diff \"снимок\"")
+			return
+		}
+		c.diffSnapshot(args[1])
+	default:
+		fmt.Println("Формат: backup [now] | backup list | backup diff \"снимок\"")
+	}
+}
+
+func (c *Cmd) listSnapshots() {
+	list, err := c.snapshots.List()
+	if err != nil {
+		fmt.Println("Ошибка:", err)
+		return
+	}
+	if len(list) == 0 {
+		fmt.Println("Снимков пока нет, каталог:", c.snapshots.Dir())
+		return
+	}
+	loc := events.ViewerLocation()
+	for i, s := range list {
+		count := fmt.Sprint(s.Events)
+		if s.Events < 0 {
+			count = "не читается"
+		}
+		fmt.Printf("%d. %s  %s  событий: %s\n", i+1, s.ID, s.Time.In(loc).Format("2006-01-02 15:04:05"), count)
+	}
+}
+
+// loadSnapshot находит снимок по ID или номеру из backup list и читает его события.
+func (c *Cmd) loadSnapshot(ref string) (*storage.Snapshot, map[string]*events.Event, bool) {
+	snapshot, err := c.snapshots.Find(ref)
+	if err == nil {
+		var list map[string]*events.Event
+		if list, err = c.snapshots.Load(snapshot.ID); err == nil {
+			return snapshot, list, true
+		}
+	}
+	if errors.Is(err, storage.ErrSnapshotNotFound) {
+		fmt.Printf("Снимок '%s' не найден, см. backup list\n", ref)
+	} else {
+		fmt.Println("Ошибка:", err)
+	}
+	return nil, nil, false
+}
+
+// diffSnapshot показывает, что изменилось в календаре после снимка.
+func (c *Cmd) diffSnapshot(ref string) {
+	snapshot, old, ok := c.loadSnapshot(ref)
+	if !ok {
+		return
+	}
+	current := c.calendar.GetEvents()
+	ids := make([]string, 0, len(old)+len(current))
+	for id := range old {
+		ids = append(ids, id)
+	}
+	for id := range current {
+		if _, ok := old[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	fmt.Printf("Изменения после снимка %s:\n", snapshot.ID)
+	changed := 0
+	for _, id := range ids {
+		before, after := old[id], current[id]
+		diff := events.Diff(before, after)
+		if len(diff) == 0 {
+			continue
+		}
+		changed++
+		switch {
+		case before == nil:
+			fmt.Printf("+ %s \"%s\" добавлено\n", id, after.Title)
+		case after == nil:
+			fmt.Printf("- %s \"%s\" удалено\n", id, before.Title)
+		default:
+			fmt.Printf("~ %s \"%s\" изменено\n", id, after.Title)
+			for _, ch := range diff {
+				fmt.Printf("    %s: \"%s\" -> \"%s\"\n", ch.Field, ch.Before, ch.After)
+			}
+		}
+	}
+	if changed == 0 {
+		fmt.Println("Нет изменений")
+	}
+}
+
+// restore заменяет календарь содержимым снимка; текущее состояние перед этим сохраняется в новый снимок.
+func (c *Cmd) restore(args []string) {
+	if len(args) < 1 {
+		fmt.Println("Формат: restore \"снимок\"")
+		return
+	}
+	if c.snapshots == nil {
+		fmt.Println("Каталог снимков недоступен")
+		return
+	}
+	snapshot, list, ok := c.loadSnapshot(args[0])
+	if !ok {
+		return
+	}
+	if current, err := c.snapshot(time.Now()); err != nil {
+		fmt.Println("Не удалось сохранить текущее состояние, восстановление отменено:", err)
+		return
+	} else if current != nil {
+		fmt.Printf("Текущее состояние сохранено в снимок %s\n", current.ID)
+	}
+	if err := c.calendar.Restore(list); err != nil {
+		log.Error("snapshot restore failed", "snapshot", snapshot.ID, "err", err)
+		if errors.Is(err, storage.ErrConflict) {
+			printConflict(err)
+		} else {
+			fmt.Println("Ошибка:", err)
+		}
+		return
+	}
+	log.Info("calendar restored from snapshot", "snapshot", snapshot.ID)
+	fmt.Printf("Календарь восстановлен из снимка %s (событий: %d)\n", snapshot.ID, len(list))
+}
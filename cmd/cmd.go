@@ -1,10 +1,11 @@
 package cmd
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/TsSol87/calendarApp/calendar"
+	"github.com/TsSol87/calendarApp/config"
+	"github.com/TsSol87/calendarApp/dateparse"
 	"github.com/TsSol87/calendarApp/events"
 	"github.com/TsSol87/calendarApp/logger"
 	"github.com/TsSol87/calendarApp/priority"
@@ -15,72 +16,49 @@ import (
 
 	"github.com/c-bata/go-prompt"
 	"github.com/google/shlex"
-	"os"
 	"strings"
 )
 
+var log = logger.Component("cmd")
+
 type Cmd struct {
-	calendar   *calendar.Calendar
-	wg         sync.WaitGroup
-	log        Log
-	logStorage storage.Store
-}
+	calendar    *calendar.Calendar
+	config      *config.Config
+	wg          sync.WaitGroup
+	history     storage.History
+	notifyMutex sync.Mutex
+	notifyHook  func(msg string)
 
-type LogEntry struct {
-	Message   string
-	Timestamp time.Time
-}
-type Log struct {
-	entries []LogEntry
-	mutex   sync.Mutex
-}
+	seqMutex  sync.Mutex
+	seqLoaded bool
+	lastSeq   int64 // номер последней записи истории
 
-func (l *Log) Print() {
-	for _, e := range l.entries {
-		fmt.Printf("CMD(Сообщение): %s\tCMD(Время): %s\n", e.Message, e.Timestamp.Format("2006-01-02T15:04:05"))
-	}
-
-}
-func NewCmd(c *calendar.Calendar) *Cmd {
-	logStorage := storage.NewJsonStorage("log_data.json")
-	cmd := &Cmd{
-		calendar:   c,
-		log:        Log{entries: make([]LogEntry, 0), mutex: sync.Mutex{}},
-		logStorage: logStorage,
-	}
-	cmd.loadLog()
-	return cmd
+	snapshots    *storage.SnapshotStore
+	lastSnapshot time.Time
 
+	exiting bool // введена команда exit
 }
 
-func (c *Cmd) Save() error {
-	data, err := json.Marshal(c.log.entries)
-	if err != nil {
-
-		return err
+func NewCmd(c *calendar.Calendar, cfg *config.Config, history storage.History) *Cmd {
+	cmd := &Cmd{
+		calendar: c,
+		config:   cfg,
+		history:  history,
 	}
-	err = c.logStorage.Save(data)
+	snapshots, err := openSnapshots(cmd)
 	if err != nil {
-		return err
+		log.Error("snapshot directory failed", "err", err)
 	}
-	return nil
-}
+	cmd.snapshots = snapshots
+	return cmd
 
-func (c *Cmd) loadLog() error {
-	data, err := c.logStorage.Load()
-	if err != nil {
-		return err
-	}
-	c.log.mutex.Lock()
-	defer c.log.mutex.Unlock()
-	err = json.Unmarshal(data, &c.log.entries)
-	if err != nil {
-		return err
-	}
-	return nil
 }
 
 func (c *Cmd) executor(input string) {
+	if ref, ok := strings.CutPrefix(strings.TrimSpace(input), "!"); ok {
+		c.rerun(ref)
+		return
+	}
 	parts, err := shlex.Split(input)
 	if err != nil {
 		fmt.Println("Ошибка разбора команды:", err)
@@ -89,13 +67,17 @@ func (c *Cmd) executor(input string) {
 	if len(parts) == 0 {
 		return
 	}
-	logger.Info(input)
-	c.log.mutex.Lock()
-	defer c.log.mutex.Unlock()
-	timestamp := time.Now()
-	entry := LogEntry{input, timestamp}
-	c.log.entries = append(c.log.entries, entry)
-	c.Save()
+	// app.log не шифруется: при включённом шифровании названия и даты событий из команды в него не попадают.
+	if c.config.Encryption == config.EncryptionNone {
+		log.Debug("command input", "input", input)
+	}
+	c.appendLog(KindCommand, input)
+	defer func(start time.Time) {
+		log.Info("command", "command", parts[0], "duration", time.Since(start))
+	}(time.Now())
+	c.annotate(input)
+	defer c.annotate("")
+	defer c.autoBackup()
 
 	cmd := strings.ToLower(parts[0])
 
@@ -112,18 +94,20 @@ func (c *Cmd) executor(input string) {
 
 		e, err := c.calendar.AddEvent(title, date, priorityStr)
 		if err != nil {
-			logMessage := fmt.Sprintf("Error adding event (title: %s, date: %s, priority: %s): %v", title, date, priorityStr, err)
-			logger.Error(logMessage)
+			log.Error("adding event failed", "err", err)
 			c.LogCapture(err)
 			if errors.Is(err, events.ErrIsValidTitle) {
-				fmt.Printf("Error: Invalid title '%s'. It must contain between 3 and 50 alphanumeric characters and spaces.\n", title)
+				fmt.Printf("Error: Invalid title '%s'. It must be 3 to 50 printable characters long.\n", title)
 
 			} else if errors.Is(err, events.ErrIsValidDate) {
-				fmt.Printf("Error: Invalid date format. Please use the format: %s\n", events.DateFormat)
+				printDateError(err)
 
 			} else if errors.Is(err, priority.ErrIsValidPriority) {
 				fmt.Println("Error: Invalid priority. Please use 'high', 'medium', or 'low'.")
 
+			} else if errors.Is(err, storage.ErrConflict) {
+				printConflict(err)
+
 			} else {
 				fmt.Printf("can't create event: %v\n", err)
 
@@ -140,9 +124,12 @@ func (c *Cmd) executor(input string) {
 		}
 		id := parts[1]
 		errDel := c.calendar.DeleteEvent(id)
-		logMessage := fmt.Sprintf("Error delete event (id: %s): %v", id, errDel)
-		logger.Error(logMessage)
 		if errDel != nil {
+			log.Error("deleting event failed", "event_id", id, "err", errDel)
+		}
+		if errors.Is(errDel, storage.ErrConflict) {
+			printConflict(errDel)
+		} else if errDel != nil {
 			fmt.Println(errDel)
 		} else {
 			fmt.Printf("Событие c ключом '%s' удалено\n", id)
@@ -159,16 +146,17 @@ func (c *Cmd) executor(input string) {
 
 		err := c.calendar.EditEvent(id, title, date, priorityStr)
 		if err != nil {
-			logMessage := fmt.Sprintf("Error update event (title: %s, date: %s, priority: %s): %v", title, date, priorityStr, err)
-			logger.Error(logMessage)
+			log.Error("updating event failed", "event_id", id, "err", err)
 			c.LogCapture(err)
 
 			if errors.Is(err, events.ErrIsValidTitle) {
-				fmt.Printf("Error: Invalid title '%s'. It must contain between 3 and 50 alphanumeric characters and spaces.\n", title)
+				fmt.Printf("Error: Invalid title '%s'. It must be 3 to 50 printable characters long.\n", title)
 			} else if errors.Is(err, events.ErrIsValidDate) {
-				fmt.Printf("Error: Invalid date format. Please use the format: %s\n", events.DateFormat)
+				printDateError(err)
 			} else if errors.Is(err, priority.ErrIsValidPriority) {
 				fmt.Println("Error: Invalid priority. Please use 'high', 'medium', or 'low'.")
+			} else if errors.Is(err, storage.ErrConflict) {
+				printConflict(err)
 			} else {
 				fmt.Printf("can't update event: %v\n", err)
 			}
@@ -177,15 +165,28 @@ func (c *Cmd) executor(input string) {
 
 		fmt.Printf("Событие c ключом '%s' изменено\n", id)
 
+	case "show":
+		c.showEvent(parts[1:])
+	case "edit":
+		c.editEvent(parts[1:])
+	case "set":
+		c.setEvent(parts[1:])
 	case "list":
-		events := c.calendar.GetEvents()
-		if len(events) == 0 {
-			fmt.Println("Список событий пуст")
-			return
-		}
-		for _, event := range events {
-			event.Print()
-		}
+		c.listEvents(parts[1:])
+	case "tag":
+		c.tagCommand(parts[1:])
+	case "tz":
+		c.convertTime(parts[1:])
+	case "planner":
+		c.showPlanner(parts[1:])
+	case "day", "week", "month":
+		c.showView(cmd, parts[1:])
+	case "config":
+		c.configCommand(parts[1:])
+	case "profile":
+		c.showProfiles()
+	case "tui":
+		c.runTUI()
 	case "reminder":
 		if len(parts) < 4 {
 			fmt.Println("Формат: reminder \"ID события\" \"сообщение\" \"дата и время\"")
@@ -198,12 +199,13 @@ func (c *Cmd) executor(input string) {
 
 		err := c.calendar.SetEventReminder(id, message, at)
 		if err != nil {
-			logMessage := fmt.Sprintf("Error adding reminder (id: %s, message: %s, at: %s): %v", id, message, at, err)
-			logger.Error(logMessage)
+			log.Error("adding reminder failed", "event_id", id, "err", err)
 			if errors.Is(err, reminder.ErrEmptyMessage) {
 				fmt.Println("Can't set reminder with empty message")
 			} else if errors.Is(err, events.ErrIsValidDate) {
-				fmt.Printf("Error: Invalid date format. Please use the format: %s\n", events.DateFormat)
+				printDateError(err)
+			} else if errors.Is(err, storage.ErrConflict) {
+				printConflict(err)
 			} else {
 				fmt.Println(err)
 			}
@@ -218,29 +220,72 @@ func (c *Cmd) executor(input string) {
 		id := parts[1]
 		errCancelReminder := c.calendar.CancelEventReminder(id)
 		if errCancelReminder != nil {
+			log.Error("cancelling reminder failed", "event_id", id, "err", errCancelReminder)
+		}
+		if errors.Is(errCancelReminder, storage.ErrConflict) {
+			printConflict(errCancelReminder)
+		} else if errCancelReminder != nil {
 			fmt.Println(errCancelReminder)
 		}
+	case "backup":
+		c.backupCommand(parts[1:])
+	case "restore":
+		c.restore(parts[1:])
+	case "log":
+		c.showEventLog(parts[1:])
+	case "revert":
+		c.revertEvent(parts[1:])
+	case "audit":
+		c.showAudit(parts[1:])
+	case "rekey":
+		c.rekey()
+	case "migrate":
+		c.migrate(parts[1:])
+	case "undo":
+		c.undo(parts[1:])
+	case "redo":
+		c.redo()
 	case "history":
-
-		c.log.Print()
+		c.showHistory(parts[1:])
 
 	case "help":
 		fmt.Println("Доступные команды:")
 		fmt.Println("  Добавить событие:\t\tadd \"название события\" \"дата и время\" \"приоритет\"")
 		fmt.Println("  Удалить событие:\t\tremove \"ID события\"")
 		fmt.Println("  Обновить событие:\t\tupdate \"ID события\" \"название события\" \"дата и время\" \"приоритет\"")
-		fmt.Println("  Показать список событий:\tlist")
+		fmt.Println("  Подробности события:\t\tshow \"ID события\"")
+		fmt.Println("  Изменить одно поле:\t\tedit \"ID события\" title|date|priority|description|location|url|attendees \"значение\" [--file \"файл\"]")
+		fmt.Println("  Изменить несколько полей:\tset \"ID события\" [--title \"название\"] [--date \"дата и время\"] [--priority приоритет] [поле=значение...]")
+		fmt.Println("  Показать список событий:\tlist [--zones] [--tag метка,...]")
+		fmt.Println("  Метки событий:\t\ttag add|remove \"ID события\" метка... | tag list")
+		fmt.Println("  План на день:\t\t\tday [\"ГГГГ-ММ-ДД\"] [--width N] [--tag метка,...]")
+		fmt.Println("  План на неделю:\t\tweek [\"ГГГГ-ММ-ДД\"] [--first mon|sun] [--width N] [--tag метка,...]")
+		fmt.Println("  Календарь на месяц:\t\tmonth [\"ГГГГ-ММ\"] [--first mon|sun] [--width N] [--tag метка,...]")
+		fmt.Println("  Время в других зонах:\t\ttz \"дата и время\" [зона...]")
+		fmt.Println("  Планировщик по зонам:\t\tplanner [\"ГГГГ-ММ-ДД\"] [--width N]")
+		fmt.Println("  Настройки:\t\t\tconfig show | config get \"ключ\" | config set \"ключ\" \"значение\"")
+		fmt.Println("  Профили и каталоги данных:\tprofile")
+		fmt.Println("  Полноэкранный режим:\t\ttui")
 		fmt.Println("  Установить напоминание:\treminder \"ID события\" \"сообщение\" \"дата и время\" \"таймер\"")
 		fmt.Println("  Отменить напоминание:\t\tcancel-reminder \"ID события\"")
-		fmt.Println("  Показать историю:\t\thistory")
+		fmt.Println("  Обновить формат данных:\tmigrate [--dry-run]")
+		fmt.Println("  Снимки календаря:\t\tbackup [now] | backup list | backup diff \"снимок\"")
+		fmt.Println("  Восстановить из снимка:\trestore \"снимок\"")
+		fmt.Println("  История события (git):\tlog \"ID события\"")
+		fmt.Println("  Вернуть версию события:\trevert \"ID события\" [версия]")
+		fmt.Println("  Журнал аудита:\t\taudit [\"ID события\"] [--json] [--output \"файл\"]")
+		fmt.Println("  Сменить ключ шифрования:\trekey")
+		fmt.Println("  Отменить последнее действие:\tundo [list]")
+		fmt.Println("  Повторить отменённое:\t\tredo")
+		fmt.Println("  Показать историю:\t\thistory [--since \"дата\"] [--until \"дата\"] [--grep \"текст\"] [--type command|notification|error] [--limit N]")
+		fmt.Println("  Повторить команду из истории:\t!номер")
 		fmt.Println("  Выйти из программы:\t\texit")
+		fmt.Println("Дата и время:", events.DateLayout(), "или, например,", dateparse.Examples)
+		fmt.Printf("Часовой пояс: %s; для события можно указать свой, например \"2025-03-10 15:00 Europe/Berlin\"\n", events.DefaultZone())
 
 	case "exit":
-		logger.System("app is closed")
-		c.calendar.Save()
-		close(c.calendar.Notification)
-		c.wg.Wait()
-		os.Exit(0)
+		// Run завершится сам, и main успеет сохранить данные и закрыть файлы.
+		c.exiting = true
 
 	default:
 		fmt.Println("Неизвестная команда:")
@@ -248,17 +293,60 @@ func (c *Cmd) executor(input string) {
 	}
 }
 
+// printConflict сообщает, что команда не выполнена: те же события изменили в другом окне программы.
+func printConflict(err error) {
+	var conflict *storage.ConflictError
+	if errors.As(err, &conflict) {
+		fmt.Println("События изменены в другом окне программы:", strings.Join(conflict.IDs, ", "))
+	} else {
+		fmt.Println("Данные изменены в другом окне программы")
+	}
+	fmt.Println("Календарь перечитан, повторите команду")
+}
+
+func printDateError(err error) {
+	fmt.Printf("Error: Invalid date: %v\n", err)
+	if errors.Is(err, dateparse.ErrAmbiguous) {
+		fmt.Println("Please specify the date more precisely.")
+	}
+	fmt.Printf("Supported formats: %s or e.g. %s\n", events.DateLayout(), dateparse.Examples)
+}
+
 func (c *Cmd) completer(d prompt.Document) []prompt.Suggest {
+	if suggestions, ok := c.argumentSuggestions(d.TextBeforeCursor()); ok {
+		return prompt.FilterHasPrefix(suggestions, strings.TrimPrefix(d.GetWordBeforeCursor(), "#"), true)
+	}
 	if strings.Contains(d.TextBeforeCursor(), " ") {
 		return []prompt.Suggest{}
 	}
 	suggestions := []prompt.Suggest{
 		{Text: "add", Description: "Добавить событие"},
 		{Text: "list", Description: "Показать все события"},
+		{Text: "tag", Description: "Добавить или снять метки события"},
+		{Text: "day", Description: "Показать события за день"},
+		{Text: "week", Description: "Показать события за неделю"},
+		{Text: "month", Description: "Показать календарь на месяц"},
 		{Text: "remove", Description: "Удалить событие"},
 		{Text: "update", Description: "Обновить событие"},
+		{Text: "show", Description: "Показать подробности события"},
+		{Text: "edit", Description: "Изменить одно поле события"},
+		{Text: "set", Description: "Изменить указанные поля события"},
+		{Text: "tz", Description: "Показать время в других зонах"},
+		{Text: "planner", Description: "Общие рабочие часы в зонах"},
+		{Text: "config", Description: "Показать или изменить настройки"},
+		{Text: "profile", Description: "Показать профили"},
+		{Text: "tui", Description: "Полноэкранный режим"},
 		{Text: "reminder", Description: "Добавить напоминание"},
 		{Text: "cancel-reminder", Description: "Отменить напоминание"},
+		{Text: "migrate", Description: "Обновить формат файла данных"},
+		{Text: "backup", Description: "Снимки календаря"},
+		{Text: "restore", Description: "Восстановить календарь из снимка"},
+		{Text: "log", Description: "История изменений события"},
+		{Text: "revert", Description: "Вернуть событие к прежней версии"},
+		{Text: "audit", Description: "Кто и как менял события"},
+		{Text: "rekey", Description: "Сменить пароль или ключ шифрования"},
+		{Text: "undo", Description: "Отменить последнее действие"},
+		{Text: "redo", Description: "Повторить отменённое действие"},
 		{Text: "help", Description: "Показать справку"},
 		{Text: "history", Description: "Показать историю"},
 		{Text: "exit", Description: "Выйти из программы"},
@@ -266,32 +354,75 @@ func (c *Cmd) completer(d prompt.Document) []prompt.Suggest {
 	return prompt.FilterHasPrefix(suggestions, d.GetWordBeforeCursor(), true)
 }
 
+// argumentSuggestions подсказывает аргументы команд: метки и поля событий.
+func (c *Cmd) argumentSuggestions(text string) ([]prompt.Suggest, bool) {
+	words := strings.Fields(text)
+	if !strings.HasSuffix(text, " ") && len(words) > 0 {
+		words = words[:len(words)-1]
+	}
+	var suggestions []prompt.Suggest
+	if labels, ok := c.tagSuggestions(words); ok {
+		for _, tag := range labels {
+			suggestions = append(suggestions, prompt.Suggest{Text: tag, Description: "Метка"})
+		}
+		return suggestions, true
+	}
+	if fields, ok := fieldSuggestions(words); ok {
+		for _, field := range fields {
+			suggestions = append(suggestions, prompt.Suggest{Text: field, Description: fieldNames[strings.TrimSuffix(field, "=")]})
+		}
+		return suggestions, true
+	}
+	return nil, false
+}
+
 func (c *Cmd) Run() {
 
 	c.wg.Add(1)
 	go func() {
 		defer c.wg.Done()
 		for msg := range c.calendar.Notification {
-			fmt.Println(msg)
-
-			timestamp := time.Now()
-			entry := LogEntry{msg, timestamp}
-			c.log.mutex.Lock()
-			c.log.entries = append(c.log.entries, entry)
-			c.log.mutex.Unlock()
-			c.Save()
+			c.notifyMutex.Lock()
+			hook := c.notifyHook
+			c.notifyMutex.Unlock()
+			if hook != nil {
+				hook(msg)
+			} else {
+				fmt.Println(msg)
+			}
+			c.appendLog(KindNotification, msg)
 		}
 		fmt.Println("Канал уведомлений закрыт")
 	}()
+	c.autoBackup()
 	p := prompt.New(
 		c.executor,
 		c.completer,
 		prompt.OptionPrefix("> "),
+		prompt.OptionSetExitCheckerOnInput(c.exitChecker),
 	)
 	p.Run()
+	log.Info("app is closed")
+	close(c.calendar.Notification)
+	c.wg.Wait()
+}
+
+// exitChecker сообщает go-prompt, что после команды exit (в том числе повторённой через !n) пора выйти из Run.
+func (c *Cmd) exitChecker(string, bool) bool {
+	return c.exiting
 }
 func (c *Cmd) LogCapture(err error) {
-	msg := err.Error()
-	c.log.entries = append(c.log.entries, LogEntry{Message: msg, Timestamp: time.Now()})
-	c.Save()
+	c.appendLog(KindError, err.Error())
+}
+
+func (c *Cmd) appendLog(kind, msg string) {
+	if err := c.appendEntry(LogEntry{Kind: kind, Message: msg, Timestamp: time.Now()}); err != nil {
+		log.Error("history append failed", "file", c.history.GetFilename(), "err", err)
+	}
+}
+
+func (c *Cmd) setNotifyHook(hook func(msg string)) {
+	c.notifyMutex.Lock()
+	c.notifyHook = hook
+	c.notifyMutex.Unlock()
 }
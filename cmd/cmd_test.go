@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TsSol87/calendarApp/calendar"
+	"github.com/TsSol87/calendarApp/config"
+	"github.com/TsSol87/calendarApp/logger"
+	"github.com/TsSol87/calendarApp/rotate"
+	"github.com/TsSol87/calendarApp/storage"
+)
+
+func newTestCmd(t *testing.T) *Cmd {
+	t.Helper()
+	dir := t.TempDir()
+	c := calendar.NewCalendar(storage.NewRepository(storage.NewJsonStorage(filepath.Join(dir, "data.json"))))
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Default()
+	cfg.Backup = config.BackupOff
+	cfg.BackupDir = filepath.Join(dir, "snapshots")
+	history := storage.NewHistoryFile(filepath.Join(dir, "history.json"), rotate.Options{})
+	t.Cleanup(func() { history.Close() })
+	return NewCmd(c, cfg, history)
+}
+
+// output перехватывает всё, что fn печатает в стандартный вывод.
+func output(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+	fn()
+	w.Close()
+	return string(<-done)
+}
+
+func TestExecutor_KeepsEventTextOutOfLogWhenEncrypted(t *testing.T) {
+	var buf bytes.Buffer
+	h, err := logger.NewHandler(&buf, logger.FormatText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.SetHandler(h)
+	logger.SetLevel(slog.LevelDebug)
+	t.Cleanup(func() {
+		logger.SetHandler(slog.DiscardHandler)
+		logger.SetLevel(slog.LevelInfo)
+	})
+
+	c := newTestCmd(t)
+	c.config.Encryption = config.EncryptionPassphrase
+	e, err := c.calendar.AddEvent("Client Acme", "2030-01-01 10:00", "high")
+	if err != nil {
+		t.Fatal(err)
+	}
+	output(t, func() {
+		c.executor(`add "Client Acme" "2030-01-02 10:00" urgent`)
+		c.executor(`update ` + e.ID + ` "Client Acme" "2030-01-02 10:00" urgent`)
+		c.executor(`reminder ` + e.ID + ` "Call Acme" "not a date"`)
+	})
+	if !strings.Contains(buf.String(), "adding event failed") || !strings.Contains(buf.String(), "updating event failed") {
+		t.Fatalf("Expected the failures to be logged, got\n%s", buf.String())
+	}
+	if strings.Contains(buf.String(), "Acme") {
+		t.Errorf("Expected no event text in the log, got\n%s", buf.String())
+	}
+}
+
+func TestExecutor_ExitLeavesRun(t *testing.T) {
+	c := newTestCmd(t)
+	if c.exitChecker("list", true) {
+		t.Fatal("Expected the prompt to keep running before exit")
+	}
+	// Раньше exit вызывал os.Exit и тест не дошёл бы до проверки.
+	c.executor("exit")
+	if !c.exitChecker("exit", true) {
+		t.Error("Expected exit to stop the prompt")
+	}
+}
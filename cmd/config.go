@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/TsSol87/calendarApp/config"
+)
+
+var restartKeys = map[string]bool{"data_file": true, "history_file": true, "log_file": true, "log_format": true, "log_max_size": true, "log_rotate_every": true, "log_max_age": true, "log_max_files": true, "log_compress": true, "history_max_size": true, "history_max_files": true, "storage": true, "compression": true, "compact_every": true, "undo_file": true, "audit_file": true, "actor": true, "undo_depth": true, "encryption": true, "key_file": true, "backup_dir": true}
+
+func (c *Cmd) configCommand(args []string) {
+	if len(args) == 0 {
+		args = []string{"show"}
+	}
+	switch args[0] {
+	case "show":
+		fmt.Println("Профиль:", c.config.Profile())
+		fmt.Println("Файл настроек:", c.config.FilePath())
+		c.config.Show(os.Stdout)
+	case "get":
+		if len(args) < 2 {
+			fmt.Println("Формат: config get \"ключ\"")
+			return
+		}
+		value, err := c.config.Get(args[1])
+		if err != nil {
+			fmt.Println("Ошибка:", err)
+			return
+		}
+		fmt.Println(value)
+	case "set":
+		if len(args) < 3 {
+			fmt.Println("Формат: config set \"ключ\" \"значение\"")
+			return
+		}
+		if err := c.setConfig(args[1], args[2]); err != nil {
+			log.Error("config set failed", "key", args[1], "value", args[2], "err", err)
+			fmt.Println("Ошибка:", err)
+			return
+		}
+		fmt.Printf("Параметр %s сохранён в %s\n", args[1], c.config.FilePath())
+		if restartKeys[args[1]] {
+			fmt.Println("Изменение вступит в силу после перезапуска")
+		}
+	default:
+		fmt.Println("Формат: config show | config get \"ключ\" | config set \"ключ\" \"значение\"")
+	}
+}
+
+// setConfig сохраняет значение в файл без переопределений из окружения и флагов,
+// а затем применяет его к текущему сеансу.
+func (c *Cmd) setConfig(key, value string) error {
+	stored, err := config.Load(c.config.Profile(), c.config.FilePath())
+	if err != nil {
+		return err
+	}
+	if err := stored.Set(key, value); err != nil {
+		return err
+	}
+	if err := stored.Validate(); err != nil {
+		return err
+	}
+	if err := stored.Save(); err != nil {
+		return err
+	}
+
+	if restartKeys[key] {
+		return nil
+	}
+	if err := c.config.Set(key, value); err != nil {
+		return err
+	}
+	return c.config.Apply()
+}
+
+func (c *Cmd) showProfiles() {
+	profiles, err := config.Profiles()
+	if err != nil {
+		fmt.Println("Ошибка:", err)
+		return
+	}
+	for _, name := range profiles {
+		marker := " "
+		if name == c.config.Profile() {
+			marker = "*"
+		}
+		fmt.Println(marker, name)
+	}
+	dataDir, _ := c.config.DataDir()
+	stateDir, _ := c.config.StateDir()
+	fmt.Println("Данные:", dataDir)
+	fmt.Println("История и журнал:", stateDir)
+	fmt.Println("Другой профиль: запустите программу с флагом --profile \"имя\"")
+}
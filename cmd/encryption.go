@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/TsSol87/calendarApp/config"
+	"github.com/TsSol87/calendarApp/storage"
+	"golang.org/x/term"
+)
+
+// EnvPassphrase позволяет передать пароль без запроса, например в скриптах.
+const EnvPassphrase = "CALENDAR_PASSPHRASE"
+
+var ErrPassphraseMismatch = errors.New("passphrases do not match")
+
+// ReadPassphrase запрашивает пароль без отображения ввода; если ввод не из терминала, читает одну строку.
+func ReadPassphrase(prompt string) ([]byte, error) {
+	fmt.Print(prompt)
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		p, err := term.ReadPassword(fd)
+		fmt.Println()
+		return p, err
+	}
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(b)
+		if n == 1 && b[0] != '\n' {
+			line = append(line, b[0])
+			continue
+		}
+		if n == 1 || len(line) > 0 {
+			return bytes.TrimRight(line, "\r"), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func readNewPassphrase() ([]byte, error) {
+	p, err := ReadPassphrase("Новый пароль для данных: ")
+	if err != nil {
+		return nil, err
+	}
+	if len(p) == 0 {
+		return nil, errors.New("passphrase must not be empty")
+	}
+	again, err := ReadPassphrase("Повторите пароль: ")
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(p, again) {
+		return nil, ErrPassphraseMismatch
+	}
+	return p, nil
+}
+
+// EncryptionSecret возвращает секрет для шифрования данных по настройкам профиля.
+// Для нового календаря пароль запрашивается дважды, а файл ключа создаётся, если его ещё нет.
+func EncryptionSecret(cfg *config.Config, create bool) (storage.Secret, error) {
+	switch cfg.Encryption {
+	case config.EncryptionPassphrase:
+		if p, ok := os.LookupEnv(EnvPassphrase); ok && p != "" {
+			return storage.Passphrase([]byte(p)), nil
+		}
+		if create {
+			p, err := readNewPassphrase()
+			if err != nil {
+				return storage.Secret{}, err
+			}
+			return storage.Passphrase(p), nil
+		}
+		p, err := ReadPassphrase("Пароль для данных: ")
+		if err != nil {
+			return storage.Secret{}, err
+		}
+		return storage.Passphrase(p), nil
+	case config.EncryptionKeyFile:
+		path, err := cfg.KeyFilePath()
+		if err != nil {
+			return storage.Secret{}, err
+		}
+		secret, err := storage.ReadKeyFile(path)
+		if errors.Is(err, os.ErrNotExist) && create {
+			if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+				return storage.Secret{}, err
+			}
+			secret, err = storage.GenerateKeyFile(path)
+			if err == nil {
+				fmt.Println("Создан файл ключа", path, "— сохраните его копию: без него данные не прочитать")
+			}
+		}
+		return secret, err
+	default:
+		return storage.Secret{}, fmt.Errorf("encryption %q does not use a secret", cfg.Encryption)
+	}
+}
+
+// encryptedStores возвращает зашифрованные хранилища данных, истории, журнала аудита и журнала отмены текущего сеанса.
+func (c *Cmd) encryptedStores() []storage.Rotator {
+	var list []storage.Rotator
+	if blob, ok := c.calendar.Repository().(*storage.BlobRepository); ok {
+		if e, ok := blob.Store().(*storage.EncryptedStorage); ok {
+			list = append(list, e)
+		}
+	}
+	for _, h := range []storage.History{c.history, c.calendar.AuditLog()} {
+		if e, ok := h.(*storage.EncryptedHistory); ok {
+			list = append(list, e)
+		}
+	}
+	if e, ok := c.calendar.UndoStore().(*storage.EncryptedStorage); ok {
+		list = append(list, e)
+	}
+	return list
+}
+
+// rekey перешифровывает все зашифрованные файлы новым паролем или новым файлом ключа.
+func (c *Cmd) rekey() {
+	stores := c.encryptedStores()
+	if len(stores) == 0 {
+		fmt.Println("Шифрование не включено: config set encryption passphrase|keyfile")
+		return
+	}
+
+	var secret storage.Secret
+	var err error
+	var keyPath, newKeyPath string
+	switch c.config.Encryption {
+	case config.EncryptionPassphrase:
+		var p []byte
+		p, err = readNewPassphrase()
+		secret = storage.Passphrase(p)
+	case config.EncryptionKeyFile:
+		keyPath, err = c.config.KeyFilePath()
+		if err == nil {
+			// Новый ключ заменяет старый только после того, как все данные перешифрованы.
+			newKeyPath = keyPath + ".new"
+			os.Remove(newKeyPath)
+			secret, err = storage.GenerateKeyFile(newKeyPath)
+		}
+	}
+	if err != nil {
+		fmt.Println("Ошибка:", err)
+		return
+	}
+
+	// Снимки шифруются тем же ключом, что и данные, поэтому перешифровываются вместе с ними.
+	// При любой ошибке уже перешифрованные файлы возвращаются к прежнему ключу.
+	var restore func() error
+	rotate := func() (err error) {
+		restore, err = storage.RotateAll(stores, secret)
+		return err
+	}
+	if c.snapshots != nil {
+		err = c.snapshots.Rewrite(rotate)
+	} else {
+		err = rotate()
+	}
+	if err != nil && restore != nil {
+		if errRestore := restore(); errRestore != nil {
+			err = errors.Join(err, errRestore)
+		}
+	}
+	if err != nil {
+		log.Error("key rotation failed", "err", err)
+		fmt.Println("Ошибка смены ключа:", err)
+		if errors.Is(err, storage.ErrRotationIncomplete) {
+			fmt.Println("Часть файлов уже зашифрована новым ключом; не удаляйте ни старый, ни новый ключ")
+			if newKeyPath != "" {
+				fmt.Println("Новый ключ сохранён в", newKeyPath)
+			}
+			return
+		}
+		if newKeyPath != "" {
+			os.Remove(newKeyPath)
+		}
+		fmt.Println("Ключ не заменён, данные по-прежнему открываются прежним ключом")
+		return
+	}
+	if newKeyPath != "" {
+		if err := os.Rename(newKeyPath, keyPath); err != nil {
+			fmt.Println("Данные перешифрованы ключом из", newKeyPath, "но заменить", keyPath, "не удалось:", err)
+			return
+		}
+	}
+	log.Info("encryption key rotated")
+	fmt.Println("Ключ шифрования заменён")
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/TsSol87/calendarApp/events"
+	"github.com/TsSol87/calendarApp/priority"
+	"github.com/TsSol87/calendarApp/storage"
+	"github.com/TsSol87/calendarApp/tags"
+)
+
+var fieldNames = map[string]string{
+	events.FieldTitle:       "Название",
+	events.FieldDate:        "Дата и время",
+	events.FieldPriority:    "Приоритет",
+	events.FieldDescription: "Описание",
+	events.FieldLocation:    "Место",
+	events.FieldURL:         "Ссылка",
+	events.FieldAttendees:   "Участники",
+}
+
+// showEvent печатает все поля события, включая многострочное описание.
+func (c *Cmd) showEvent(args []string) {
+	if len(args) != 1 {
+		fmt.Println("Формат: show \"ID события\"")
+		return
+	}
+	e, ok := c.calendar.GetEvents()[args[0]]
+	if !ok {
+		fmt.Printf("Событие c ключом '%s' не найдено\n", args[0])
+		return
+	}
+
+	loc := events.ViewerLocation()
+	date := e.StartAt.In(loc).Format(events.DateLayout())
+	if e.Zone != "" && e.Zone != loc.String() {
+		date += fmt.Sprintf(" (%s %s)", e.StartAt.In(e.Location()).Format("15:04"), e.Zone)
+	}
+	fmt.Printf("%-13s %s\n", "Событие:", e.Title)
+	fmt.Printf("%-13s %s\n", "ID:", e.ID)
+	fmt.Printf("%-13s %s\n", "Дата:", date)
+	fmt.Printf("%-13s %s\n", "Приоритет:", e.Priority)
+	if e.Place != "" {
+		fmt.Printf("%-13s %s\n", "Место:", e.Place)
+	}
+	if e.URL != "" {
+		fmt.Printf("%-13s %s\n", "Ссылка:", e.URL)
+	}
+	if len(e.Attendees) > 0 {
+		fmt.Printf("%-13s %s\n", "Участники:", strings.Join(e.Attendees, ", "))
+	}
+	if len(e.Tags) > 0 {
+		fmt.Printf("%-13s %s\n", "Метки:", tags.Format(e.Tags))
+	}
+	fmt.Printf("%-13s %s\n", "Напоминание:", e.Reminder.Format(loc))
+	if e.Description != "" {
+		fmt.Println("Описание:")
+		for _, line := range strings.Split(e.Description, "\n") {
+			fmt.Println("  " + line)
+		}
+	}
+}
+
+const editUsage = "Формат: edit \"ID события\" поле \"значение\" | edit \"ID события\" description --file \"файл\"\n" +
+	"Поля: title, date, priority, description, location, url, attendees; \"\" очищает поле, \\n в описании — перенос строки"
+
+// editEvent меняет одно поле события, не требуя остальных, как update.
+func (c *Cmd) editEvent(args []string) {
+	fs := newFlagSet("edit")
+	file := fs.String("file", "", "взять значение из файла")
+	positional, err := parseArgs(fs, args)
+	if err != nil || len(positional) < 2 || len(positional) > 3 || (len(positional) == 3) == (*file != "") {
+		fmt.Println(editUsage)
+		return
+	}
+	id, field := positional[0], strings.ToLower(positional[1])
+
+	var value string
+	if *file != "" {
+		data, err := os.ReadFile(*file)
+		if err != nil {
+			fmt.Println("Ошибка:", err)
+			return
+		}
+		value = string(data)
+	} else {
+		value = positional[2]
+		if field == events.FieldDescription {
+			value = strings.ReplaceAll(value, `\n`, "\n")
+		}
+	}
+
+	c.patchEvent(id, events.Patch{field: value})
+}
+
+const setUsage = "Формат: set \"ID события\" [--title \"название\"] [--date \"дата и время\"] [--priority приоритет] [поле=значение...]\n" +
+	"Поля: title, date, priority, description, location, url, attendees; меняются только указанные"
+
+// setEvent меняет сразу несколько полей события, заданных флагами или парами поле=значение.
+func (c *Cmd) setEvent(args []string) {
+	p := events.Patch{}
+	fs := newFlagSet("set")
+	for _, field := range events.Fields {
+		fs.Func(field, fieldNames[field], func(value string) error {
+			p[field] = value
+			return nil
+		})
+	}
+	positional, err := parseArgs(fs, args)
+	if err != nil || len(positional) < 1 {
+		fmt.Println(setUsage)
+		return
+	}
+	id := positional[0]
+	for _, pair := range positional[1:] {
+		field, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			fmt.Println(setUsage)
+			return
+		}
+		p[strings.ToLower(field)] = value
+	}
+	if len(p) == 0 {
+		fmt.Println(setUsage)
+		return
+	}
+	if value, ok := p[events.FieldDescription]; ok {
+		p[events.FieldDescription] = strings.ReplaceAll(value, `\n`, "\n")
+	}
+	c.patchEvent(id, p)
+}
+
+// patchEvent применяет изменения и печатает, какие поля изменились.
+func (c *Cmd) patchEvent(id string, p events.Patch) {
+	changes, err := c.calendar.PatchEvent(id, p)
+	if err != nil {
+		log.Error("patching event failed", "event_id", id, "err", err)
+		c.LogCapture(err)
+		printFieldError(err, p[events.FieldTitle])
+		return
+	}
+	if len(changes) == 0 {
+		fmt.Printf("Событие c ключом '%s' не изменилось\n", id)
+		return
+	}
+	fmt.Printf("Событие c ключом '%s' изменено:\n", id)
+	for _, ch := range changes {
+		fmt.Printf("     %s: \"%s\" -> \"%s\"\n", ch.Field, ch.Before, ch.After)
+	}
+}
+
+func printFieldError(err error, title string) {
+	switch {
+	case errors.Is(err, events.ErrIsValidTitle):
+		fmt.Printf("Error: Invalid title '%s'. It must be 3 to 50 printable characters long.\n", title)
+	case errors.Is(err, events.ErrIsValidDate):
+		printDateError(err)
+	case errors.Is(err, priority.ErrIsValidPriority):
+		fmt.Println("Error: Invalid priority. Please use 'high', 'medium', or 'low'.")
+	case errors.Is(err, events.ErrIsValidField), errors.Is(err, events.ErrUnknownField):
+		fmt.Println("Error:", err)
+	case errors.Is(err, storage.ErrConflict):
+		printConflict(err)
+	default:
+		fmt.Printf("can't update event: %v\n", err)
+	}
+}
+
+// fieldSuggestions подсказывает поля в "edit ID ..." и пары поле= в "set ID ...".
+func fieldSuggestions(words []string) ([]string, bool) {
+	switch {
+	case len(words) == 2 && words[0] == "edit":
+		return events.Fields, true
+	case len(words) >= 2 && words[0] == "set":
+		list := make([]string, len(events.Fields))
+		for i, field := range events.Fields {
+			list[i] = field + "="
+		}
+		return list, true
+	}
+	return nil, false
+}
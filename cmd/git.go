@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/TsSol87/calendarApp/events"
+	"github.com/TsSol87/calendarApp/storage"
+)
+
+// annotate передаёт хранилищу введённую команду, чтобы она попала в описание изменения.
+func (c *Cmd) annotate(input string) {
+	if a, ok := c.calendar.Repository().(storage.Annotator); ok {
+		a.Annotate(input)
+	}
+}
+
+func (c *Cmd) eventHistory(id string) ([]storage.Revision, bool) {
+	v, ok := c.calendar.Repository().(storage.Versioned)
+	if !ok {
+		fmt.Println("История изменений доступна только с хранилищем git: config set storage git")
+		return nil, false
+	}
+	list, err := v.History(id)
+	if err != nil {
+		log.Error("event history failed", "event_id", id, "err", err)
+		fmt.Println("Ошибка:", err)
+		return nil, false
+	}
+	if len(list) == 0 {
+		fmt.Printf("Для события '%s' нет истории\n", id)
+		return nil, false
+	}
+	return list, true
+}
+
+// showEventLog печатает версии события от новых к старым и поля, изменённые в каждой.
+func (c *Cmd) showEventLog(args []string) {
+	if len(args) < 1 {
+		fmt.Println("Формат: log \"ID события\"")
+		return
+	}
+	list, ok := c.eventHistory(args[0])
+	if !ok {
+		return
+	}
+	loc := events.ViewerLocation()
+	for i, rev := range list {
+		summary, _, _ := strings.Cut(rev.Message, "\n")
+		fmt.Printf("%d. %s  %s  %s  %s\n", i+1, rev.Hash[:7], rev.Time.In(loc).Format("2006-01-02 15:04:05"), rev.Author, summary)
+		if _, command, ok := strings.Cut(rev.Message, "Command: "); ok {
+			fmt.Printf("     команда: %s\n", strings.TrimSpace(command))
+		}
+		var older *events.Event
+		if i+1 < len(list) {
+			older = list[i+1].Event
+		}
+		if older == nil || rev.Event == nil {
+			continue
+		}
+		for _, ch := range events.Diff(older, rev.Event) {
+			fmt.Printf("     %s: \"%s\" -> \"%s\"\n", ch.Field, ch.Before, ch.After)
+		}
+	}
+}
+
+// findRevision ищет версию по номеру из log (1 — последняя) или по началу хеша коммита.
+func findRevision(list []storage.Revision, ref string) (*storage.Revision, error) {
+	if n, err := strconv.Atoi(ref); err == nil && len(ref) < 4 {
+		if n < 1 || n > len(list) {
+			return nil, fmt.Errorf("revision %d is out of range 1-%d", n, len(list))
+		}
+		return &list[n-1], nil
+	}
+	var found *storage.Revision
+	for i := range list {
+		if strings.HasPrefix(list[i].Hash, ref) {
+			if found != nil {
+				return nil, fmt.Errorf("revision %q is ambiguous", ref)
+			}
+			found = &list[i]
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("revision %q not found", ref)
+	}
+	return found, nil
+}
+
+// revertEvent возвращает событие к версии из log; по умолчанию — к состоянию до последнего изменения.
+func (c *Cmd) revertEvent(args []string) {
+	if len(args) < 1 {
+		fmt.Println("Формат: revert \"ID события\" [версия]")
+		return
+	}
+	id := args[0]
+	list, ok := c.eventHistory(id)
+	if !ok {
+		return
+	}
+	ref := "2"
+	if len(args) > 1 {
+		ref = args[1]
+	}
+	if len(list) < 2 && len(args) < 2 {
+		fmt.Println("У события нет более ранних версий")
+		return
+	}
+	rev, err := findRevision(list, ref)
+	if err != nil {
+		fmt.Println("Ошибка:", err)
+		return
+	}
+	if len(events.Diff(c.calendar.GetEvents()[id], rev.Event)) == 0 {
+		fmt.Println("Событие уже в этом состоянии")
+		return
+	}
+
+	if err := c.calendar.Revert(id, rev.Event); err != nil {
+		log.Error("event revert failed", "event_id", id, "revision", rev.Hash, "err", err)
+		if errors.Is(err, storage.ErrConflict) {
+			printConflict(err)
+		} else {
+			fmt.Println("Ошибка:", err)
+		}
+		return
+	}
+	if rev.Event == nil {
+		fmt.Printf("Событие '%s' удалено, как в версии %s\n", id, rev.Hash[:7])
+		return
+	}
+	fmt.Printf("Событие '%s' возвращено к версии %s\n", id, rev.Hash[:7])
+}
@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TsSol87/calendarApp/events"
+	"github.com/TsSol87/calendarApp/storage"
+)
+
+// Виды записей истории. У записей, сделанных до появления видов, вид пустой.
+const (
+	KindCommand      = "command"
+	KindNotification = "notification"
+	KindError        = "error"
+)
+
+var historyKinds = []string{KindCommand, KindNotification, KindError}
+
+var kindNames = map[string]string{KindCommand: "команда", KindNotification: "уведомление", KindError: "ошибка"}
+
+// LogEntry — запись истории. Seq — постоянный номер записи: по нему команду можно повторить через !n,
+// и он не меняется, когда старые части истории удаляются по правилам хранения.
+type LogEntry struct {
+	Seq       int64  `json:",omitempty"`
+	Kind      string `json:",omitempty"`
+	Message   string
+	Timestamp time.Time
+}
+
+// loadSeq находит номер последней записи истории. Записям, сделанным до появления номеров,
+// номера присваиваются по порядку, если историю можно переписать; иначе их нельзя повторить через !n.
+// Вызывается под seqMutex.
+func (c *Cmd) loadSeq() error {
+	if c.seqLoaded {
+		return nil
+	}
+	var last int64
+	unnumbered := 0
+	err := c.history.Scan(func(record []byte) error {
+		var e LogEntry
+		if err := json.Unmarshal(record, &e); err != nil {
+			return err
+		}
+		if e.Seq == 0 {
+			unnumbered++
+		}
+		last = max(last, e.Seq)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if r, ok := c.history.(storage.HistoryRewriter); ok && unnumbered > 0 {
+		err := r.Rewrite(func(record []byte) ([]byte, error) {
+			var e LogEntry
+			if err := json.Unmarshal(record, &e); err != nil || e.Seq != 0 {
+				return record, err
+			}
+			last++
+			e.Seq = last
+			return json.Marshal(e)
+		})
+		if err != nil {
+			return fmt.Errorf("can't number history entries: %w", err)
+		}
+	}
+	c.lastSeq, c.seqLoaded = last, true
+	return nil
+}
+
+// appendEntry дописывает запись в историю со следующим номером.
+func (c *Cmd) appendEntry(e LogEntry) error {
+	c.seqMutex.Lock()
+	defer c.seqMutex.Unlock()
+	if err := c.loadSeq(); err != nil {
+		return err
+	}
+	e.Seq = c.lastSeq + 1
+	record, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := c.history.Append(record); err != nil {
+		return err
+	}
+	c.lastSeq = e.Seq
+	return nil
+}
+
+// scanHistory передаёт fn записи истории по порядку, от старых к новым.
+func (c *Cmd) scanHistory(fn func(e LogEntry) error) error {
+	c.seqMutex.Lock()
+	err := c.loadSeq()
+	c.seqMutex.Unlock()
+	if err != nil {
+		return err
+	}
+	return c.history.Scan(func(record []byte) error {
+		var e LogEntry
+		if err := json.Unmarshal(record, &e); err != nil {
+			return err
+		}
+		return fn(e)
+	})
+}
+
+// historyFilter отбирает записи по времени [since, until), подстроке без учёта регистра и виду.
+type historyFilter struct {
+	since time.Time
+	until time.Time
+	grep  string
+	kind  string
+}
+
+func (f historyFilter) match(e LogEntry) bool {
+	if !f.since.IsZero() && e.Timestamp.Before(f.since) {
+		return false
+	}
+	if !f.until.IsZero() && !e.Timestamp.Before(f.until) {
+		return false
+	}
+	if f.kind != "" && e.Kind != f.kind {
+		return false
+	}
+	return f.grep == "" || strings.Contains(strings.ToLower(e.Message), f.grep)
+}
+
+// parseHistoryTime понимает даты, как в add. Дата в виде 2006-01-02 означает весь день:
+// --since 2030-01-10 — с начала дня, --until 2030-01-10 — до его конца. Остальные формы,
+// в том числе "in 2h" и "9am", задают точный момент.
+func parseHistoryTime(s string, end bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	day, err := time.ParseInLocation("2006-01-02", s, events.ViewerLocation())
+	if err != nil {
+		return events.TimeParse(s)
+	}
+	if end {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, nil
+}
+
+func newHistoryFilter(since, until, grep, kind string) (historyFilter, error) {
+	f := historyFilter{grep: strings.ToLower(grep), kind: kind}
+	if kind != "" && !slices.Contains(historyKinds, kind) {
+		return f, fmt.Errorf("unknown history type %q (use %s)", kind, strings.Join(historyKinds, ", "))
+	}
+	var err error
+	if f.since, err = parseHistoryTime(since, false); err != nil {
+		return f, fmt.Errorf("--since: %w", err)
+	}
+	if f.until, err = parseHistoryTime(until, true); err != nil {
+		return f, fmt.Errorf("--until: %w", err)
+	}
+	return f, nil
+}
+
+const historyUsage = "Формат: history [--since \"дата\"] [--until \"дата\"] [--grep \"текст\"] [--type command|notification|error] [--limit N]"
+
+// showHistory печатает записи истории, подходящие под фильтры. История читается потоком:
+// без --limit записи печатаются сразу, с --limit в памяти держатся только последние N.
+func (c *Cmd) showHistory(args []string) {
+	fs := newFlagSet("history")
+	since := fs.String("since", "", "записи начиная с даты")
+	until := fs.String("until", "", "записи до даты включительно")
+	grep := fs.String("grep", "", "записи, содержащие текст")
+	kind := fs.String("type", "", "вид записей: command, notification или error")
+	limit := fs.Int("limit", 0, "только последние N записей")
+	positional, err := parseArgs(fs, args)
+	if err != nil || len(positional) > 0 || *limit < 0 {
+		fmt.Println(historyUsage)
+		return
+	}
+	filter, err := newHistoryFilter(*since, *until, *grep, *kind)
+	if err != nil {
+		fmt.Println("Ошибка:", err)
+		return
+	}
+
+	var last []LogEntry
+	found := 0
+	err = c.scanHistory(func(e LogEntry) error {
+		if !filter.match(e) {
+			return nil
+		}
+		found++
+		if *limit == 0 {
+			printLogEntry(e)
+			return nil
+		}
+		last = append(last, e)
+		if len(last) > *limit {
+			last = last[1:]
+		}
+		return nil
+	})
+	for _, e := range last {
+		printLogEntry(e)
+	}
+	if err != nil {
+		log.Error("history reading failed", "file", c.history.GetFilename(), "err", err)
+		fmt.Println("Ошибка чтения истории:", err)
+		return
+	}
+	if found == 0 {
+		fmt.Println("Записей не найдено")
+	}
+}
+
+func printLogEntry(e LogEntry) {
+	kind := kindNames[e.Kind]
+	if kind == "" {
+		kind = "-"
+	}
+	n := "-"
+	if e.Seq > 0 {
+		n = strconv.FormatInt(e.Seq, 10)
+	}
+	fmt.Printf("%5s  %s  %-12s  %s\n", n, e.Timestamp.In(events.ViewerLocation()).Format("2006-01-02 15:04:05"), kind, e.Message)
+}
+
+// rerun повторяет команду из истории по её номеру в выводе history: !n. Если записи с таким номером
+// уже нет или номер встречается дважды (историю дописывали два сеанса), команда не выполняется.
+func (c *Cmd) rerun(ref string) {
+	n, err := strconv.ParseInt(strings.TrimSpace(ref), 10, 64)
+	if err != nil || n < 1 {
+		fmt.Println("Формат: !номер записи из history")
+		return
+	}
+	var found *LogEntry
+	matches := 0
+	err = c.scanHistory(func(e LogEntry) error {
+		if e.Seq == n {
+			found = &e
+			matches++
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error("history reading failed", "file", c.history.GetFilename(), "err", err)
+		fmt.Println("Ошибка чтения истории:", err)
+		return
+	}
+	if found == nil {
+		fmt.Printf("Записи %d нет в истории\n", n)
+		return
+	}
+	if matches > 1 {
+		fmt.Printf("Номер %d в истории не единственный, команда не повторена\n", n)
+		return
+	}
+	if found.Kind != KindCommand && found.Kind != "" {
+		fmt.Printf("Запись %d — %s, а не команда\n", n, kindNames[found.Kind])
+		return
+	}
+	fmt.Println(found.Message)
+	c.executor(found.Message)
+}
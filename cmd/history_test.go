@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TsSol87/calendarApp/dateparse"
+	"github.com/TsSol87/calendarApp/events"
+	"github.com/TsSol87/calendarApp/rotate"
+	"github.com/TsSol87/calendarApp/storage"
+)
+
+func TestHistoryFilter_Match(t *testing.T) {
+	at := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	entry := LogEntry{Kind: KindCommand, Message: "add \"Team Meeting\"", Timestamp: at}
+	tests := []struct {
+		name   string
+		filter historyFilter
+		want   bool
+	}{
+		{"empty filter", historyFilter{}, true},
+		{"since is inclusive", historyFilter{since: at}, true},
+		{"since after entry", historyFilter{since: at.Add(time.Second)}, false},
+		{"until is exclusive", historyFilter{until: at}, false},
+		{"until after entry", historyFilter{until: at.Add(time.Second)}, true},
+		{"grep ignores case", historyFilter{grep: "team meeting"}, true},
+		{"grep mismatch", historyFilter{grep: "delete"}, false},
+		{"kind", historyFilter{kind: KindCommand}, true},
+		{"other kind", historyFilter{kind: KindError}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.match(entry); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+	if (historyFilter{kind: KindCommand}).match(LogEntry{Message: "old", Timestamp: at}) {
+		t.Error("Expected entries without kind not to match --type")
+	}
+}
+
+func TestParseHistoryTime(t *testing.T) {
+	loc := events.ViewerLocation()
+	day := time.Date(2030, 1, 10, 0, 0, 0, 0, loc)
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	tests := []struct {
+		value string
+		end   bool
+		want  time.Time
+	}{
+		{"", false, time.Time{}},
+		{"2030-01-10", false, day},
+		// Дата без времени в --until включает весь день.
+		{"2030-01-10", true, day.AddDate(0, 0, 1)},
+		{"2030-01-10 15:30", false, day.Add(15*time.Hour + 30*time.Minute)},
+		{"2030-01-10 15:30", true, day.Add(15*time.Hour + 30*time.Minute)},
+		// Остальные формы задают точный момент, даже без двоеточия.
+		{"9am", false, today.Add(9 * time.Hour)},
+		{"9am", true, today.Add(9 * time.Hour)},
+		{"yesterday", false, today.AddDate(0, 0, -1).Add(dateparse.DefaultHour * time.Hour)},
+	}
+	for _, tt := range tests {
+		got, err := parseHistoryTime(tt.value, tt.end)
+		if err != nil {
+			t.Errorf("%q: %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("%q (end %v): expected %v, got %v", tt.value, tt.end, tt.want, got)
+		}
+	}
+	before := time.Now()
+	got, err := parseHistoryTime("in 2h", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Before(before.Add(2*time.Hour)) || got.After(time.Now().Add(2*time.Hour)) {
+		t.Errorf("\"in 2h\": expected two hours from now, got %v", got)
+	}
+	if _, err := parseHistoryTime("not a date", false); err == nil {
+		t.Error("Expected an error for an invalid date")
+	}
+}
+
+// historyNumbers возвращает номера записей из вывода history.
+func historyNumbers(out string) []string {
+	var numbers []string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			numbers = append(numbers, fields[0])
+		}
+	}
+	return numbers
+}
+
+func TestShowHistory_Limit(t *testing.T) {
+	c := newTestCmd(t)
+	c.appendLog(KindCommand, "list")
+	c.appendLog(KindNotification, "Meeting")
+	c.appendLog(KindCommand, "help")
+	c.appendLog(KindError, "failed")
+	c.appendLog(KindCommand, "week")
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "1 2 3 4 5"},
+		{[]string{"--limit", "2"}, "4 5"},
+		{[]string{"--limit", "10"}, "1 2 3 4 5"},
+		// Лимит применяется после фильтров, а номера остаются номерами в полной истории.
+		{[]string{"--type", "command", "--limit", "2"}, "3 5"},
+	}
+	for _, tt := range tests {
+		out := output(t, func() { c.showHistory(tt.args) })
+		if got := strings.Join(historyNumbers(out), " "); got != tt.want {
+			t.Errorf("%v: expected entries %s, got %s\n%s", tt.args, tt.want, got, out)
+		}
+	}
+	if out := output(t, func() { c.showHistory([]string{"--limit", "-1"}) }); !strings.HasPrefix(out, "Формат: history") {
+		t.Errorf("Expected usage for a negative limit, got %q", out)
+	}
+}
+
+func TestRerun(t *testing.T) {
+	c := newTestCmd(t)
+	c.appendLog(KindNotification, "add \"Reminder\" \"2030-01-01 10:00\" high")
+	c.appendLog(KindError, "failed")
+	c.appendLog(KindCommand, "add \"Meeting\" \"2030-01-01 10:00\" high")
+
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"1", "Запись 1 — уведомление, а не команда"},
+		{"2", "Запись 2 — ошибка, а не команда"},
+		{"9", "Записи 9 нет в истории"},
+		{"x", "Формат: !номер записи из history"},
+	}
+	for _, tt := range tests {
+		if out := output(t, func() { c.rerun(tt.ref) }); strings.TrimSpace(out) != tt.want {
+			t.Errorf("!%s: expected %q, got %q", tt.ref, tt.want, out)
+		}
+	}
+	if n := len(c.calendar.GetEvents()); n != 0 {
+		t.Fatalf("Expected rejected entries not to run, got %d events", n)
+	}
+
+	output(t, func() { c.rerun("3") })
+	list := c.calendar.GetEvents()
+	if len(list) != 1 {
+		t.Fatalf("Expected the command to run again, got %d events", len(list))
+	}
+	for _, e := range list {
+		if e.Title != "Meeting" {
+			t.Errorf("Expected the Meeting event, got %q", e.Title)
+		}
+	}
+}
+
+// memHistory — история в памяти, из которой тест может удалять старые записи, как это делает ротация.
+type memHistory struct {
+	records [][]byte
+}
+
+func (h *memHistory) Append(record []byte) error {
+	h.records = append(h.records, slices.Clone(record))
+	return nil
+}
+
+func (h *memHistory) Scan(fn func(record []byte) error) error {
+	for _, r := range h.records {
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *memHistory) GetFilename() string { return "memory" }
+
+func TestRerun_NumbersSurviveRetention(t *testing.T) {
+	c := newTestCmd(t)
+	history := &memHistory{}
+	c.history = history
+	c.appendLog(KindCommand, "add \"Old\" \"2030-01-01 10:00\" high")
+	c.appendLog(KindCommand, "add \"Meeting\" \"2030-01-02 10:00\" high")
+	c.appendLog(KindCommand, "list")
+	// Ротация удалила самую старую запись: остальные номера не сдвигаются.
+	history.records = history.records[1:]
+
+	if got := strings.Join(historyNumbers(output(t, func() { c.showHistory(nil) })), " "); got != "2 3" {
+		t.Errorf("Expected entries 2 3, got %s", got)
+	}
+	if out := output(t, func() { c.rerun("1") }); strings.TrimSpace(out) != "Записи 1 нет в истории" {
+		t.Errorf("Expected the removed entry to be refused, got %q", out)
+	}
+	if n := len(c.calendar.GetEvents()); n != 0 {
+		t.Fatalf("Expected nothing to run for a removed entry, got %d events", n)
+	}
+	output(t, func() { c.rerun("2") })
+	for _, e := range c.calendar.GetEvents() {
+		if e.Title != "Meeting" {
+			t.Errorf("Expected !2 to add Meeting, got %q", e.Title)
+		}
+	}
+	c.appendLog(KindCommand, "help")
+	if got := strings.Join(historyNumbers(output(t, func() { c.showHistory([]string{"--limit", "1"}) })), " "); got != "5" {
+		t.Errorf("Expected new entries to continue the numbering, got %s", got)
+	}
+}
+
+func TestRerun_RefusesDuplicateNumber(t *testing.T) {
+	c := newTestCmd(t)
+	history := &memHistory{}
+	c.history = history
+	for _, title := range []string{"First", "Second"} {
+		record, _ := json.Marshal(LogEntry{Seq: 1, Kind: KindCommand, Message: "add \"" + title + "\" \"2030-01-01 10:00\" high", Timestamp: time.Now()})
+		history.records = append(history.records, record)
+	}
+	if out := output(t, func() { c.rerun("1") }); strings.TrimSpace(out) != "Номер 1 в истории не единственный, команда не повторена" {
+		t.Errorf("Expected a duplicate number to be refused, got %q", out)
+	}
+	if n := len(c.calendar.GetEvents()); n != 0 {
+		t.Errorf("Expected nothing to run, got %d events", n)
+	}
+}
+
+func TestHistory_NumbersLegacyEntries(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "history.json")
+	legacy := storage.NewHistoryFile(filename, rotate.Options{})
+	for _, message := range []string{"list", "week"} {
+		record, _ := json.Marshal(LogEntry{Message: message, Timestamp: time.Now()})
+		if err := legacy.Append(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	legacy.Close()
+
+	c := newTestCmd(t)
+	history := storage.NewHistoryFile(filename, rotate.Options{})
+	t.Cleanup(func() { history.Close() })
+	c.history = history
+	c.appendLog(KindCommand, "help")
+
+	if got := strings.Join(historyNumbers(output(t, func() { c.showHistory(nil) })), " "); got != "1 2 3" {
+		t.Errorf("Expected entries 1 2 3, got %s", got)
+	}
+	var seqs []int64
+	history.Scan(func(record []byte) error {
+		var e LogEntry
+		if err := json.Unmarshal(record, &e); err != nil {
+			return err
+		}
+		seqs = append(seqs, e.Seq)
+		return nil
+	})
+	if !slices.Equal(seqs, []int64{1, 2, 3}) {
+		t.Errorf("Expected the numbers to be saved in the file, got %v", seqs)
+	}
+}
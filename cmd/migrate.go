@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/TsSol87/calendarApp/storage"
+)
+
+func (c *Cmd) migrate(args []string) {
+	fs := newFlagSet("migrate")
+	dryRun := fs.Bool("dry-run", false, "только показать шаги миграции")
+	if _, err := parseArgs(fs, args); err != nil {
+		fmt.Println("Формат: migrate [--dry-run]")
+		return
+	}
+
+	m, ok := c.calendar.Repository().(storage.Migrator)
+	if !ok {
+		fmt.Println("Хранилище не поддерживает миграции")
+		return
+	}
+	report, err := m.Migrate(*dryRun)
+	if err != nil {
+		log.Error("migration failed", "err", err)
+		fmt.Println("Ошибка миграции:", err)
+		return
+	}
+
+	fmt.Printf("Версия данных: %d, текущая версия: %d, событий: %d\n", report.From, report.To, report.Events)
+	if !report.Pending() {
+		fmt.Println("Данные уже в актуальном формате")
+		return
+	}
+	if *dryRun {
+		fmt.Println("Будут выполнены шаги:")
+	} else {
+		fmt.Println("Выполнены шаги:")
+	}
+	for _, step := range report.Steps {
+		fmt.Println("  " + step)
+	}
+	if !*dryRun {
+		log.Info("data migrated", "from", report.From, "to", report.To)
+		fmt.Println("Данные обновлены, предыдущая версия сохранена в резервной копии")
+	}
+}
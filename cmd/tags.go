@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/TsSol87/calendarApp/storage"
+	"github.com/TsSol87/calendarApp/tags"
+)
+
+const tagUsage = "Формат: tag add \"ID события\" метка... | tag remove \"ID события\" метка... | tag list"
+
+// tagCommand добавляет и снимает метки событий или показывает все метки с числом событий.
+func (c *Cmd) tagCommand(args []string) {
+	if len(args) == 1 && args[0] == "list" {
+		c.listTags()
+		return
+	}
+	if len(args) < 3 || (args[0] != "add" && args[0] != "remove") {
+		fmt.Println(tagUsage)
+		return
+	}
+	id, labels := args[1], args[2:]
+
+	var changed []string
+	var err error
+	if args[0] == "add" {
+		changed, err = c.calendar.TagEvent(id, labels)
+	} else {
+		changed, err = c.calendar.UntagEvent(id, labels)
+	}
+	if err != nil {
+		log.Error("changing tags failed", "event_id", id, "err", err)
+		c.LogCapture(err)
+		if errors.Is(err, tags.ErrIsValidTag) {
+			fmt.Println("Error:", err)
+		} else if errors.Is(err, storage.ErrConflict) {
+			printConflict(err)
+		} else {
+			fmt.Println(err)
+		}
+		return
+	}
+
+	switch {
+	case len(changed) == 0 && args[0] == "add":
+		fmt.Printf("У события c ключом '%s' уже есть эти метки\n", id)
+	case len(changed) == 0:
+		fmt.Printf("У события c ключом '%s' нет этих меток\n", id)
+	case args[0] == "add":
+		fmt.Printf("Событию c ключом '%s' добавлены метки %s\n", id, tags.Format(changed))
+	default:
+		fmt.Printf("С события c ключом '%s' сняты метки %s\n", id, tags.Format(changed))
+	}
+}
+
+func (c *Cmd) listTags() {
+	counts := c.calendar.Tags()
+	if len(counts) == 0 {
+		fmt.Println("Меток нет")
+		return
+	}
+	for _, tag := range sortedTags(counts) {
+		fmt.Printf("%s  %d\n", tags.Paint(tag), counts[tag])
+	}
+}
+
+func sortedTags(counts map[string]int) []string {
+	list := make([]string, 0, len(counts))
+	for tag := range counts {
+		list = append(list, tag)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// tagSuggestions подсказывает метки в "tag add|remove ID ..." и после флага --tag.
+func (c *Cmd) tagSuggestions(words []string) ([]string, bool) {
+	switch {
+	case len(words) >= 3 && words[0] == "tag" && (words[1] == "add" || words[1] == "remove"):
+	case len(words) > 0 && (words[len(words)-1] == "--tag" || words[len(words)-1] == "-tag"):
+	default:
+		return nil, false
+	}
+	return sortedTags(c.calendar.Tags()), true
+}
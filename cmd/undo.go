@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/TsSol87/calendarApp/calendar"
+	"github.com/TsSol87/calendarApp/events"
+	"github.com/TsSol87/calendarApp/storage"
+)
+
+var operationNames = map[string]string{
+	calendar.OpAdd:            "добавление события",
+	calendar.OpUpdate:         "изменение события",
+	calendar.OpRemove:         "удаление события",
+	calendar.OpSetReminder:    "установка напоминания",
+	calendar.OpCancelReminder: "отмена напоминания",
+	calendar.OpTag:            "добавление меток",
+	calendar.OpUntag:          "снятие меток",
+	calendar.OpRevert:         "возврат версии события",
+	calendar.OpUndo:           "отмена действия",
+	calendar.OpRedo:           "повтор действия",
+	calendar.OpRestore:        "восстановление из снимка",
+}
+
+func describe(op *calendar.Operation) string {
+	return fmt.Sprintf("%s \"%s\" (%s)", operationNames[op.Op], op.Title, op.ID)
+}
+
+func (c *Cmd) undo(args []string) {
+	if len(args) > 0 && args[0] == "list" {
+		list := c.calendar.UndoHistory()
+		if len(list) == 0 {
+			fmt.Println("Нечего отменять")
+			return
+		}
+		for i, op := range list {
+			fmt.Printf("%d. %s, %s\n", i+1, describe(&op), op.At.In(events.ViewerLocation()).Format("2006-01-02 15:04:05"))
+		}
+		return
+	}
+
+	op, err := c.calendar.Undo()
+	if errors.Is(err, calendar.ErrNothingToUndo) {
+		fmt.Println("Нечего отменять")
+		return
+	}
+	if errors.Is(err, storage.ErrConflict) {
+		printConflict(err)
+		return
+	}
+	if err != nil {
+		log.Error("undo failed", "err", err)
+		fmt.Println("Ошибка:", err)
+		if op == nil {
+			return
+		}
+	}
+	fmt.Println("Отменено:", describe(op))
+}
+
+func (c *Cmd) redo() {
+	op, err := c.calendar.Redo()
+	if errors.Is(err, calendar.ErrNothingToRedo) {
+		fmt.Println("Нечего повторять")
+		return
+	}
+	if errors.Is(err, storage.ErrConflict) {
+		printConflict(err)
+		return
+	}
+	if err != nil {
+		log.Error("redo failed", "err", err)
+		fmt.Println("Ошибка:", err)
+		if op == nil {
+			return
+		}
+	}
+	fmt.Println("Повторено:", describe(op))
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/TsSol87/calendarApp/events"
+	"github.com/TsSol87/calendarApp/tags"
+	"github.com/TsSol87/calendarApp/tui"
+	"github.com/TsSol87/calendarApp/views"
+)
+
+func (c *Cmd) eventList() []*events.Event {
+	list := make([]*events.Event, 0)
+	for _, e := range c.calendar.GetEvents() {
+		list = append(list, e)
+	}
+	return list
+}
+
+func (c *Cmd) viewOptions(args []string) (views.Options, []string, error) {
+	fs := newFlagSet("view")
+	first := fs.String("first", "", "первый день недели")
+	width := fs.Int("width", 0, "ширина вывода")
+	tagFilter := fs.String("tag", "", "только события с метками (через запятую)")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return views.Options{}, nil, err
+	}
+	labels, err := tags.Parse(*tagFilter)
+	if err != nil {
+		return views.Options{}, nil, err
+	}
+
+	loc, err := events.Location()
+	if err != nil {
+		return views.Options{}, nil, err
+	}
+	opt := views.Options{Width: *width, FirstWeekday: c.config.Weekday(), Location: loc, Tags: labels}
+	if opt.Width == 0 {
+		opt.Width = views.TerminalWidth()
+	}
+	if *first != "" {
+		opt.FirstWeekday, err = views.ParseWeekday(*first)
+		if err != nil {
+			return views.Options{}, nil, err
+		}
+	}
+	return opt, positional, nil
+}
+
+func parseViewDate(positional []string, layout string, loc *time.Location) (time.Time, error) {
+	if len(positional) == 0 {
+		return time.Now().In(loc), nil
+	}
+	t, err := time.ParseInLocation(layout, positional[0], loc)
+	if err == nil {
+		return t, nil
+	}
+	if t, errParse := events.TimeParse(positional[0]); errParse == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q, expected format %s", positional[0], layout)
+}
+
+func (c *Cmd) showView(name string, args []string) {
+	opt, positional, err := c.viewOptions(args)
+	if err != nil {
+		fmt.Println("Ошибка:", err)
+		return
+	}
+
+	layout := "2006-01-02"
+	if name == "month" {
+		layout = "2006-01"
+	}
+	at, err := parseViewDate(positional, layout, opt.Location)
+	if err != nil {
+		fmt.Println("Ошибка:", err)
+		return
+	}
+
+	from, to := viewRange(name, at.In(opt.Location), opt)
+	list, err := c.calendar.EventsBetween(from, to)
+	if err != nil {
+		fmt.Println("Ошибка:", err)
+		return
+	}
+
+	switch name {
+	case "day":
+		views.Day(os.Stdout, list, at, opt)
+	case "week":
+		views.Week(os.Stdout, list, at, opt)
+	case "month":
+		views.Month(os.Stdout, list, at, opt)
+	}
+}
+
+// viewRange возвращает период, события которого нужны представлению.
+func viewRange(name string, at time.Time, opt views.Options) (time.Time, time.Time) {
+	switch name {
+	case "week":
+		from := views.StartOfWeek(at, opt.FirstWeekday)
+		return from, from.AddDate(0, 0, 7)
+	case "month":
+		from := time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, at.Location())
+		return from, from.AddDate(0, 1, 0)
+	default:
+		from := views.StartOfDay(at)
+		return from, from.AddDate(0, 0, 1)
+	}
+}
+
+func (c *Cmd) runTUI() {
+	opt, _, err := c.viewOptions(nil)
+	if err != nil {
+		fmt.Println("Ошибка:", err)
+		return
+	}
+	t := tui.New(c.calendar, opt)
+	c.setNotifyHook(t.Notify)
+	defer c.setNotifyHook(nil)
+	if err := t.Run(); err != nil {
+		log.Error("TUI failed", "err", err)
+		fmt.Println("Ошибка:", err)
+	}
+}
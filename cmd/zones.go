@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/TsSol87/calendarApp/events"
+	"github.com/TsSol87/calendarApp/tags"
+	"github.com/TsSol87/calendarApp/views"
+)
+
+func (c *Cmd) zoneLocations(extra []string) ([]*time.Location, error) {
+	locations, err := c.config.Locations()
+	if err != nil {
+		return nil, err
+	}
+	for _, zone := range extra {
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load time zone '%s': %w", zone, err)
+		}
+		locations = append(locations, loc)
+	}
+	return locations, nil
+}
+
+func (c *Cmd) listEvents(args []string) {
+	fs := newFlagSet("list")
+	showZones := fs.Bool("zones", false, "показать время в дополнительных зонах")
+	tagFilter := fs.String("tag", "", "только события с метками (через запятую)")
+	if _, err := parseArgs(fs, args); err != nil {
+		fmt.Println("Ошибка:", err)
+		return
+	}
+	labels, err := tags.Parse(*tagFilter)
+	if err != nil {
+		fmt.Println("Ошибка:", err)
+		return
+	}
+
+	list := events.FilterTags(c.eventList(), labels)
+	if len(list) == 0 && len(labels) > 0 {
+		fmt.Println("Событий с метками", tags.Format(labels), "нет")
+		return
+	}
+	if len(list) == 0 {
+		fmt.Println("Список событий пуст")
+		return
+	}
+
+	var zones []*time.Location
+	if *showZones {
+		var err error
+		zones, err = c.zoneLocations(nil)
+		if err != nil {
+			fmt.Println("Ошибка:", err)
+			return
+		}
+		if len(zones) == 0 {
+			fmt.Println("Дополнительные зоны не настроены (параметр zones в конфигурации, CALENDAR_ZONES или -zones)")
+		}
+	}
+
+	for _, e := range list {
+		e.Print()
+		if len(zones) > 0 {
+			views.Clock(os.Stdout, e.StartAt, zones)
+		}
+	}
+}
+
+func (c *Cmd) convertTime(args []string) {
+	if len(args) < 1 {
+		fmt.Println("Формат: tz \"дата и время\" [зона...]")
+		return
+	}
+	at, err := events.TimeParse(args[0])
+	if err != nil {
+		printDateError(err)
+		return
+	}
+	zones, err := c.zoneLocations(args[1:])
+	if err != nil {
+		fmt.Println("Ошибка:", err)
+		return
+	}
+	seen := make(map[string]bool)
+	unique := make([]*time.Location, 0)
+	for _, loc := range append([]*time.Location{at.Location(), events.ViewerLocation()}, zones...) {
+		if !seen[loc.String()] {
+			seen[loc.String()] = true
+			unique = append(unique, loc)
+		}
+	}
+	views.Clock(os.Stdout, at, unique)
+}
+
+func (c *Cmd) showPlanner(args []string) {
+	opt, positional, err := c.viewOptions(args)
+	if err != nil {
+		fmt.Println("Ошибка:", err)
+		return
+	}
+	day, err := parseViewDate(positional, "2006-01-02", opt.Location)
+	if err != nil {
+		fmt.Println("Ошибка:", err)
+		return
+	}
+	zones, err := c.zoneLocations(nil)
+	if err != nil {
+		fmt.Println("Ошибка:", err)
+		return
+	}
+	if len(zones) == 0 {
+		fmt.Println("Дополнительные зоны не настроены (параметр zones в конфигурации, CALENDAR_ZONES или -zones)")
+		return
+	}
+	hours := views.WorkHours{Start: c.config.WorkHoursStart, End: c.config.WorkHoursEnd}
+	views.Planner(os.Stdout, day, zones, hours, opt)
+}
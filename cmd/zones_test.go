@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConvertTime(t *testing.T) {
+	c := newTestCmd(t)
+	tests := []struct {
+		name string
+		at   string
+		want string
+	}{
+		{"before spring forward", "2025-03-30 01:30 Europe/Berlin",
+			"  Europe/Berlin        Вс 30.03.2025 01:30 (UTC+01:00)\n" +
+				"  Asia/Irkutsk         Вс 30.03.2025 08:30 (UTC+08:00)\n" +
+				"  America/New_York     Сб 29.03.2025 20:30 (UTC-04:00)\n"},
+		{"after spring forward", "2025-03-30 03:30 Europe/Berlin",
+			"  Europe/Berlin        Вс 30.03.2025 03:30 (UTC+02:00)\n" +
+				"  Asia/Irkutsk         Вс 30.03.2025 09:30 (UTC+08:00)\n" +
+				"  America/New_York     Сб 29.03.2025 21:30 (UTC-04:00)\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := output(t, func() { c.convertTime([]string{tt.at, "America/New_York", "Europe/Berlin"}) })
+			if got != tt.want {
+				t.Errorf("Expected\n%s\ngot\n%s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestListEvents_Zones(t *testing.T) {
+	c := newTestCmd(t)
+	if _, err := c.calendar.AddEvent("Meeting", "2030-07-01 12:00 UTC", "high"); err != nil {
+		t.Fatal(err)
+	}
+
+	got := output(t, func() { c.listEvents([]string{"--zones"}) })
+	if !strings.Contains(got, "Дополнительные зоны не настроены") {
+		t.Errorf("Expected a hint about missing zones, got %q", got)
+	}
+
+	c.config.Zones = []string{"America/New_York", "Asia/Tokyo"}
+	got = output(t, func() { c.listEvents([]string{"--zones"}) })
+	for _, want := range []string{
+		"America/New_York     Пн 01.07.2030 08:00 (UTC-04:00)",
+		"Asia/Tokyo           Пн 01.07.2030 21:00 (UTC+09:00)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in\n%s", want, got)
+		}
+	}
+	if got := output(t, func() { c.listEvents(nil) }); strings.Contains(got, "America/New_York") {
+		t.Errorf("Expected no zones without --zones, got\n%s", got)
+	}
+}
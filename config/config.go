@@ -0,0 +1,473 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TsSol87/calendarApp/events"
+	"github.com/TsSol87/calendarApp/logger"
+	"github.com/TsSol87/calendarApp/rotate"
+	"github.com/TsSol87/calendarApp/storage"
+	"github.com/TsSol87/calendarApp/tags"
+	"github.com/TsSol87/calendarApp/views"
+)
+
+const (
+	AppName     = "calendarApp"
+	FileName    = "config.json"
+	EnvPrefix   = "CALENDAR_"
+	EnvTimeZone = "CALENDAR_TZ"
+)
+
+const megabyte = 1 << 20
+
+var ErrUnknownKey = errors.New("unknown config key")
+
+var StorageKinds = []string{"json", "zip", "sqlite", "journal", "git"}
+
+const (
+	EncryptionNone       = "none"
+	EncryptionPassphrase = "passphrase"
+	EncryptionKeyFile    = "keyfile"
+)
+
+var EncryptionModes = []string{EncryptionNone, EncryptionPassphrase, EncryptionKeyFile}
+
+const (
+	BackupOff      = "off"
+	BackupSchedule = "schedule"
+	BackupOnChange = "change"
+)
+
+var BackupModes = []string{BackupOff, BackupSchedule, BackupOnChange}
+
+// EncryptableStorage — бэкенды, которые хранят календарь одним документом и могут быть зашифрованы целиком.
+var EncryptableStorage = []string{"json", "zip"}
+
+type Config struct {
+	DataFile       string            `json:"data_file"`
+	HistoryFile    string            `json:"history_file"`
+	LogFile        string            `json:"log_file"`
+	LogLevel       string            `json:"log_level"`
+	LogFormat      string            `json:"log_format"`
+	LogMaxSize     int               `json:"log_max_size"`
+	LogRotateEvery string            `json:"log_rotate_every"`
+	LogMaxAge      string            `json:"log_max_age"`
+	LogMaxFiles    int               `json:"log_max_files"`
+	LogCompress    bool              `json:"log_compress"`
+	HistoryMaxSize int               `json:"history_max_size"`
+	HistoryFiles   int               `json:"history_max_files"`
+	UndoFile       string            `json:"undo_file"`
+	AuditFile      string            `json:"audit_file"`
+	Actor          string            `json:"actor"`
+	UndoDepth      int               `json:"undo_depth"`
+	Storage        string            `json:"storage"`
+	Compression    string            `json:"compression"`
+	CompactEvery   int               `json:"compact_every"`
+	Encryption     string            `json:"encryption"`
+	KeyFile        string            `json:"key_file"`
+	Backup         string            `json:"backup"`
+	BackupInterval string            `json:"backup_interval"`
+	BackupDir      string            `json:"backup_dir"`
+	BackupLast     int               `json:"backup_last"`
+	BackupHourly   int               `json:"backup_hourly"`
+	BackupDaily    int               `json:"backup_daily"`
+	BackupWeekly   int               `json:"backup_weekly"`
+	TimeZone       string            `json:"time_zone"`
+	DateFormat     string            `json:"date_format"`
+	FirstWeekday   string            `json:"first_weekday"`
+	Zones          []string          `json:"zones"`
+	WorkHoursStart int               `json:"work_hours_start"`
+	WorkHoursEnd   int               `json:"work_hours_end"`
+	TagColors      map[string]string `json:"tag_colors"`
+
+	path    string
+	profile string
+}
+
+func Default() *Config {
+	return &Config{
+		DataFile:       "calendar_data.json",
+		HistoryFile:    "log_data.json",
+		LogFile:        "app.log",
+		LogLevel:       "info",
+		LogFormat:      logger.FormatText,
+		LogMaxSize:     10,
+		LogRotateEvery: "24h",
+		LogMaxAge:      "720h",
+		LogMaxFiles:    7,
+		LogCompress:    true,
+		HistoryMaxSize: 1,
+		HistoryFiles:   5,
+		UndoFile:       "undo_data.json",
+		AuditFile:      "audit_log.json",
+		UndoDepth:      50,
+		Storage:        "json",
+		Compression:    string(storage.CompressionDeflate),
+		CompactEvery:   storage.DefaultCompactEvery,
+		Encryption:     EncryptionNone,
+		KeyFile:        "calendar.key",
+		Backup:         BackupSchedule,
+		BackupInterval: "1h",
+		BackupDir:      "snapshots",
+		BackupLast:     10,
+		BackupHourly:   24,
+		BackupDaily:    7,
+		BackupWeekly:   4,
+		TimeZone:       events.TimeZone,
+		DateFormat:     events.DateFormat,
+		FirstWeekday:   "mon",
+		Zones:          []string{},
+		WorkHoursStart: 9,
+		WorkHoursEnd:   18,
+		TagColors:      map[string]string{},
+	}
+}
+
+// Load читает файл конфигурации профиля (по умолчанию из каталога XDG_CONFIG_HOME);
+// отсутствующий файл не считается ошибкой.
+func Load(profile, path string) (*Config, error) {
+	if profile == "" {
+		profile = DefaultProfile
+	}
+	if err := ValidateProfile(profile); err != nil {
+		return nil, err
+	}
+	if path == "" {
+		var err error
+		path, err = Path(profile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	cfg := Default()
+	cfg.path = path
+	cfg.profile = profile
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func (c *Config) FilePath() string {
+	return c.path
+}
+
+func (c *Config) Save() error {
+	if c.path == "" {
+		return errors.New("config file path is not set")
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, append(data, '\n'), 0644)
+}
+
+func Keys() []string {
+	keys := make([]string, 0, len(settings))
+	for _, s := range settings {
+		keys = append(keys, s.key)
+	}
+	return keys
+}
+
+func lookup(key string) (setting, error) {
+	for _, s := range settings {
+		if s.key == key {
+			return s, nil
+		}
+	}
+	return setting{}, fmt.Errorf("%w %q (available: %s)", ErrUnknownKey, key, strings.Join(Keys(), ", "))
+}
+
+func (c *Config) Get(key string) (string, error) {
+	s, err := lookup(key)
+	if err != nil {
+		return "", err
+	}
+	return s.get(c), nil
+}
+
+func (c *Config) Set(key, value string) error {
+	s, err := lookup(key)
+	if err != nil {
+		return err
+	}
+	if err := s.set(c, value); err != nil {
+		return fmt.Errorf("invalid value %q for %s: %w", value, key, err)
+	}
+	return nil
+}
+
+func (c *Config) Show(w io.Writer) {
+	for _, s := range settings {
+		fmt.Fprintf(w, "%-18s = %s\n", s.key, s.get(c))
+	}
+}
+
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(key)
+}
+
+func (c *Config) ApplyEnv() error {
+	aliases := map[string]string{"time_zone": EnvTimeZone}
+	for _, s := range settings {
+		value, ok := os.LookupEnv(EnvName(s.key))
+		if !ok {
+			value, ok = os.LookupEnv(aliases[s.key])
+		}
+		if !ok || value == "" {
+			continue
+		}
+		if err := c.Set(s.key, value); err != nil {
+			return fmt.Errorf("environment %s: %w", EnvName(s.key), err)
+		}
+	}
+	return nil
+}
+
+type Overrides [][2]string
+
+// RegisterFlags добавляет флаг командной строки для каждого параметра (data_file -> -data-file).
+// Значения запоминаются и применяются после загрузки файла, поэтому флаги имеют наивысший приоритет.
+func RegisterFlags(fs *flag.FlagSet) *Overrides {
+	overrides := &Overrides{}
+	for _, s := range settings {
+		key := s.key
+		fs.Func(strings.ReplaceAll(key, "_", "-"), s.usage, func(value string) error {
+			if _, err := lookup(key); err != nil {
+				return err
+			}
+			*overrides = append(*overrides, [2]string{key, value})
+			return nil
+		})
+	}
+	fs.Func("tz", "alias for -time-zone", func(value string) error {
+		*overrides = append(*overrides, [2]string{"time_zone", value})
+		return nil
+	})
+	return overrides
+}
+
+func (o *Overrides) Apply(c *Config) error {
+	for _, kv := range *o {
+		if err := c.Set(kv[0], kv[1]); err != nil {
+			return fmt.Errorf("flag -%s: %w", strings.ReplaceAll(kv[0], "_", "-"), err)
+		}
+	}
+	return nil
+}
+
+func (c *Config) Locations() ([]*time.Location, error) {
+	locations := make([]*time.Location, 0, len(c.Zones))
+	for _, zone := range c.Zones {
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load time zone '%s': %w", zone, err)
+		}
+		locations = append(locations, loc)
+	}
+	return locations, nil
+}
+
+func (c *Config) Weekday() time.Weekday {
+	wd, err := views.ParseWeekday(c.FirstWeekday)
+	if err != nil {
+		return time.Monday
+	}
+	return wd
+}
+
+func (c *Config) Validate() error {
+	var errs []error
+	for _, key := range []string{"data_file", "history_file", "log_file", "undo_file", "audit_file"} {
+		if value, _ := c.Get(key); strings.TrimSpace(value) == "" {
+			errs = append(errs, fmt.Errorf("%s must not be empty", key))
+		}
+	}
+	if _, err := logger.ParseLevel(c.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("log_level: %w", err))
+	}
+	if !contains(logger.Formats, c.LogFormat) {
+		errs = append(errs, fmt.Errorf("log_format must be one of %s, got %q", strings.Join(logger.Formats, ", "), c.LogFormat))
+	}
+	for _, key := range []string{"log_rotate_every", "log_max_age"} {
+		value, _ := c.Get(key)
+		if d, err := time.ParseDuration(value); err != nil || d < 0 {
+			errs = append(errs, fmt.Errorf("%s must be a duration such as 24h, or 0 to disable, got %q", key, value))
+		}
+	}
+	if c.LogMaxSize < 0 || c.LogMaxFiles < 0 || c.HistoryMaxSize < 0 || c.HistoryFiles < 0 {
+		errs = append(errs, errors.New("log_max_size, log_max_files, history_max_size and history_max_files must not be negative"))
+	}
+	if !contains(StorageKinds, c.Storage) {
+		errs = append(errs, fmt.Errorf("storage must be one of %s, got %q", strings.Join(StorageKinds, ", "), c.Storage))
+	}
+	if err := storage.ValidateCompression(storage.Compression(c.Compression)); err != nil {
+		errs = append(errs, fmt.Errorf("compression: %w", err))
+	}
+	if !contains(EncryptionModes, c.Encryption) {
+		errs = append(errs, fmt.Errorf("encryption must be one of %s, got %q", strings.Join(EncryptionModes, ", "), c.Encryption))
+	} else if c.Encryption != EncryptionNone && !contains(EncryptableStorage, c.Storage) {
+		errs = append(errs, fmt.Errorf("encryption is supported only with %s storage", strings.Join(EncryptableStorage, " or ")))
+	}
+	if c.Encryption == EncryptionKeyFile && strings.TrimSpace(c.KeyFile) == "" {
+		errs = append(errs, errors.New("key_file must not be empty with keyfile encryption"))
+	}
+	if c.UndoDepth < 0 {
+		errs = append(errs, fmt.Errorf("undo_depth must not be negative, got %d", c.UndoDepth))
+	}
+	if !contains(BackupModes, c.Backup) {
+		errs = append(errs, fmt.Errorf("backup must be one of %s, got %q", strings.Join(BackupModes, ", "), c.Backup))
+	}
+	if d, err := time.ParseDuration(c.BackupInterval); err != nil || d <= 0 {
+		errs = append(errs, fmt.Errorf("backup_interval must be a positive duration such as 1h or 30m, got %q", c.BackupInterval))
+	}
+	if strings.TrimSpace(c.BackupDir) == "" {
+		errs = append(errs, errors.New("backup_dir must not be empty"))
+	}
+	if c.BackupLast < 0 || c.BackupHourly < 0 || c.BackupDaily < 0 || c.BackupWeekly < 0 {
+		errs = append(errs, errors.New("backup_last, backup_hourly, backup_daily and backup_weekly must not be negative"))
+	}
+	if c.CompactEvery <= 0 {
+		errs = append(errs, fmt.Errorf("compact_every must be positive, got %d", c.CompactEvery))
+	}
+	if _, err := time.LoadLocation(c.TimeZone); err != nil {
+		errs = append(errs, fmt.Errorf("time_zone: %w", err))
+	}
+	if err := events.ValidateDateFormat(c.DateFormat); err != nil {
+		errs = append(errs, fmt.Errorf("date_format: %w", err))
+	}
+	if _, err := views.ParseWeekday(c.FirstWeekday); err != nil {
+		errs = append(errs, fmt.Errorf("first_weekday: %w", err))
+	}
+	if _, err := c.Locations(); err != nil {
+		errs = append(errs, fmt.Errorf("zones: %w", err))
+	}
+	if c.WorkHoursStart < 0 || c.WorkHoursEnd > 24 || c.WorkHoursStart >= c.WorkHoursEnd {
+		errs = append(errs, fmt.Errorf("invalid work hours %d-%d", c.WorkHoursStart, c.WorkHoursEnd))
+	}
+	if _, err := tags.ParseColors(c.TagColorList()); err != nil {
+		errs = append(errs, fmt.Errorf("tag_colors: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// StorageOptions возвращает настройки хранилища для storage.Open.
+func (c *Config) StorageOptions() storage.Options {
+	return storage.Options{
+		Compression:  storage.Compression(c.Compression),
+		CompactEvery: c.CompactEvery,
+		History: rotate.Options{
+			MaxSize:  int64(c.HistoryMaxSize) * megabyte,
+			MaxFiles: c.HistoryFiles,
+			Compress: true,
+		},
+	}
+}
+
+// BackupEvery возвращает интервал между снимками по расписанию.
+func (c *Config) BackupEvery() time.Duration {
+	d, err := time.ParseDuration(c.BackupInterval)
+	if err != nil || d <= 0 {
+		return time.Hour
+	}
+	return d
+}
+
+// Retention возвращает правила хранения снимков.
+func (c *Config) Retention() storage.Retention {
+	return storage.Retention{Last: c.BackupLast, Hourly: c.BackupHourly, Daily: c.BackupDaily, Weekly: c.BackupWeekly}
+}
+
+// LogOptions возвращает настройки журнала приложения для logger.Init.
+func (c *Config) LogOptions() logger.Options {
+	every, _ := time.ParseDuration(c.LogRotateEvery)
+	age, _ := time.ParseDuration(c.LogMaxAge)
+	return logger.Options{Level: c.LogLevel, Format: c.LogFormat, Rotate: rotate.Options{
+		MaxSize:  int64(c.LogMaxSize) * megabyte,
+		Every:    every,
+		MaxAge:   age,
+		MaxFiles: c.LogMaxFiles,
+		Compress: c.LogCompress,
+	}}
+}
+
+// ActorName возвращает имя, которым подписываются записи журнала аудита: actor или имя пользователя ОС.
+func (c *Config) ActorName() string {
+	if c.Actor != "" {
+		return c.Actor
+	}
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return "unknown"
+}
+
+// Apply проверяет конфигурацию и передаёт глобальные настройки пакетам events, tags и logger.
+func (c *Config) Apply() error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	level, err := logger.ParseLevel(c.LogLevel)
+	if err != nil {
+		return err
+	}
+	logger.SetLevel(level)
+	if err := events.SetDefaultZone(c.TimeZone); err != nil {
+		return err
+	}
+	if err := tags.SetColors(c.TagColorList()); err != nil {
+		return err
+	}
+	return events.SetDateFormat(c.DateFormat)
+}
+
+// TagColorList возвращает цвета меток в виде "personal=green,work=blue".
+func (c *Config) TagColorList() string {
+	list := make([]string, 0, len(c.TagColors))
+	for tag, color := range c.TagColors {
+		list = append(list, tag+"="+color)
+	}
+	sort.Strings(list)
+	return strings.Join(list, ",")
+}
+
+func SplitList(s string) []string {
+	list := make([]string, 0)
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad_MissingFileReturnsDefaults(t *testing.T) {
+	cfg, err := Load(DefaultProfile, filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("Expected no error for missing config file, got: %v", err)
+	}
+	if cfg.DataFile != Default().DataFile {
+		t.Errorf("Expected default data file, got %q", cfg.DataFile)
+	}
+}
+
+func TestPrecedence_FileEnvFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	err := os.WriteFile(path, []byte(`{"time_zone": "Europe/Berlin", "data_file": "file.json", "storage": "zip"}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CALENDAR_DATA_FILE", "env.json")
+	t.Setenv(EnvTimeZone, "Europe/Moscow")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	overrides := RegisterFlags(fs)
+	if err := fs.Parse([]string{"-tz", "America/New_York"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(DefaultProfile, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.ApplyEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if err := overrides.Apply(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Storage != "zip" {
+		t.Errorf("Expected storage from file, got %q", cfg.Storage)
+	}
+	if cfg.DataFile != "env.json" {
+		t.Errorf("Expected data file from environment, got %q", cfg.DataFile)
+	}
+	if cfg.TimeZone != "America/New_York" {
+		t.Errorf("Expected time zone from flag, got %q", cfg.TimeZone)
+	}
+}
+
+func TestValidate_InvalidValues(t *testing.T) {
+	cfg := Default()
+	cfg.Storage = "floppy"
+	cfg.TimeZone = "Mars/Olympus"
+	cfg.DateFormat = "15:04"
+	cfg.WorkHoursStart = 20
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("Expected validation error, got none")
+	}
+	if err := Default().Validate(); err != nil {
+		t.Errorf("Expected default config to be valid, got: %v", err)
+	}
+}
+
+func TestSetGet(t *testing.T) {
+	cfg := Default()
+	if err := cfg.Set("zones", "Europe/Moscow, Europe/Berlin"); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := cfg.Get("zones"); value != "Europe/Moscow,Europe/Berlin" {
+		t.Errorf("Unexpected zones value %q", value)
+	}
+	if err := cfg.Set("tag_colors", "work=blue, Personal=green"); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := cfg.Get("tag_colors"); value != "personal=green,work=blue" {
+		t.Errorf("Unexpected tag_colors value %q", value)
+	}
+	if err := cfg.Set("tag_colors", "work=pink"); err == nil {
+		t.Errorf("Expected error for an unknown tag color")
+	}
+	if err := cfg.Set("work_hours_end", "late"); err == nil {
+		t.Errorf("Expected error for non-numeric work hours")
+	}
+	if _, err := cfg.Get("colour"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestProfilePaths(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", filepath.Join(root, "data"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(root, "state"))
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "config"))
+
+	cfg, err := Load("work", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := filepath.Join(root, "config", AppName, "profiles", "work", FileName); cfg.FilePath() != expected {
+		t.Errorf("Expected config path %q, got %q", expected, cfg.FilePath())
+	}
+	if p, _ := cfg.DataPath(); p != filepath.Join(root, "data", AppName, "profiles", "work", "calendar_data.json") {
+		t.Errorf("Unexpected data path %q", p)
+	}
+	if p, _ := cfg.LogPath(); p != filepath.Join(root, "state", AppName, "profiles", "work", "app.log") {
+		t.Errorf("Unexpected log path %q", p)
+	}
+
+	if _, err := Load("../etc", ""); err == nil {
+		t.Errorf("Expected error for invalid profile name")
+	}
+}
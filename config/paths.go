@@ -0,0 +1,239 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+)
+
+const (
+	DefaultProfile = "default"
+	EnvProfile     = "CALENDAR_PROFILE"
+	profilesDir    = "profiles"
+)
+
+var profileRe = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,32}$`)
+
+func ValidateProfile(name string) error {
+	if !profileRe.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use 1-32 letters, digits, '-' or '_'", name)
+	}
+	return nil
+}
+
+func baseDir(env string, fallback ...string) (string, error) {
+	if dir := os.Getenv(env); dir != "" {
+		return filepath.Join(dir, AppName), nil
+	}
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("LOCALAPPDATA"); dir != "" {
+			return filepath.Join(dir, AppName), nil
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("can't find home directory: %w", err)
+	}
+	return filepath.Join(append([]string{home}, append(fallback, AppName)...)...), nil
+}
+
+func profileDir(base, profile string) string {
+	if profile == "" || profile == DefaultProfile {
+		return base
+	}
+	return filepath.Join(base, profilesDir, profile)
+}
+
+func configBase() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, AppName), nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("can't find config directory: %w", err)
+	}
+	return filepath.Join(dir, AppName), nil
+}
+
+func Dir(profile string) (string, error) {
+	base, err := configBase()
+	if err != nil {
+		return "", err
+	}
+	return profileDir(base, profile), nil
+}
+
+func Path(profile string) (string, error) {
+	dir, err := Dir(profile)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, FileName), nil
+}
+
+func DataDir(profile string) (string, error) {
+	base, err := baseDir("XDG_DATA_HOME", ".local", "share")
+	if err != nil {
+		return "", err
+	}
+	return profileDir(base, profile), nil
+}
+
+func StateDir(profile string) (string, error) {
+	base, err := baseDir("XDG_STATE_HOME", ".local", "state")
+	if err != nil {
+		return "", err
+	}
+	return profileDir(base, profile), nil
+}
+
+// Profiles возвращает профили, для которых уже есть данные или настройки.
+func Profiles() ([]string, error) {
+	found := map[string]bool{DefaultProfile: true}
+	for _, dir := range []func(string) (string, error){Dir, DataDir} {
+		base, err := dir(DefaultProfile)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := os.ReadDir(filepath.Join(base, profilesDir))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		for _, e := range entries {
+			if e.IsDir() && ValidateProfile(e.Name()) == nil {
+				found[e.Name()] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (c *Config) Profile() string {
+	if c.profile == "" {
+		return DefaultProfile
+	}
+	return c.profile
+}
+
+func resolve(dir func(string) (string, error), profile, name string) (string, error) {
+	if filepath.IsAbs(name) {
+		return name, nil
+	}
+	base, err := dir(profile)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, name), nil
+}
+
+func (c *Config) DataDir() (string, error) {
+	return DataDir(c.Profile())
+}
+
+func (c *Config) StateDir() (string, error) {
+	return StateDir(c.Profile())
+}
+
+func (c *Config) DataPath() (string, error) {
+	return resolve(DataDir, c.Profile(), c.DataFile)
+}
+
+func (c *Config) HistoryPath() (string, error) {
+	return resolve(StateDir, c.Profile(), c.HistoryFile)
+}
+
+func (c *Config) LogPath() (string, error) {
+	return resolve(StateDir, c.Profile(), c.LogFile)
+}
+
+func (c *Config) UndoPath() (string, error) {
+	return resolve(StateDir, c.Profile(), c.UndoFile)
+}
+
+// AuditPath возвращает журнал аудита; он лежит рядом с данными, потому что описывает их изменения.
+func (c *Config) AuditPath() (string, error) {
+	return resolve(DataDir, c.Profile(), c.AuditFile)
+}
+
+// BackupPath возвращает каталог снимков календаря.
+func (c *Config) BackupPath() (string, error) {
+	return resolve(DataDir, c.Profile(), c.BackupDir)
+}
+
+// KeyFilePath возвращает путь к файлу ключа; относительный путь считается от каталога настроек профиля.
+func (c *Config) KeyFilePath() (string, error) {
+	return resolve(Dir, c.Profile(), c.KeyFile)
+}
+
+// EnsureDirs создаёт каталоги для файлов данных, истории и журнала.
+func (c *Config) EnsureDirs() error {
+	for _, path := range []func() (string, error){c.DataPath, c.HistoryPath, c.LogPath, c.UndoPath, c.AuditPath} {
+		p, err := path()
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AdoptLegacyFiles копирует файлы, которые раньше создавались в текущем каталоге,
+// в каталоги XDG профиля по умолчанию, если там ещё ничего нет.
+func (c *Config) AdoptLegacyFiles() ([]string, error) {
+	if c.Profile() != DefaultProfile {
+		return nil, nil
+	}
+	var adopted []string
+	for _, item := range []struct {
+		name string
+		path func() (string, error)
+	}{{c.DataFile, c.DataPath}, {c.HistoryFile, c.HistoryPath}} {
+		if filepath.IsAbs(item.name) {
+			continue
+		}
+		target, err := item.path()
+		if err != nil {
+			return adopted, err
+		}
+		if _, err := os.Stat(target); !errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if _, err := os.Stat(item.name); err != nil {
+			continue
+		}
+		if err := copyFile(item.name, target); err != nil {
+			return adopted, err
+		}
+		adopted = append(adopted, target)
+	}
+	return adopted, nil
+}
+
+func copyFile(from, to string) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(to, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/TsSol87/calendarApp/tags"
+)
+
+type setting struct {
+	key   string
+	usage string
+	get   func(c *Config) string
+	set   func(c *Config, value string) error
+}
+
+func stringSetting(key, usage string, field func(c *Config) *string) setting {
+	return setting{
+		key:   key,
+		usage: usage,
+		get:   func(c *Config) string { return *field(c) },
+		set: func(c *Config, value string) error {
+			*field(c) = value
+			return nil
+		},
+	}
+}
+
+func intSetting(key, usage string, field func(c *Config) *int) setting {
+	return setting{
+		key:   key,
+		usage: usage,
+		get:   func(c *Config) string { return strconv.Itoa(*field(c)) },
+		set: func(c *Config, value string) error {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return err
+			}
+			*field(c) = n
+			return nil
+		},
+	}
+}
+
+func boolSetting(key, usage string, field func(c *Config) *bool) setting {
+	return setting{
+		key:   key,
+		usage: usage,
+		get:   func(c *Config) string { return strconv.FormatBool(*field(c)) },
+		set: func(c *Config, value string) error {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return err
+			}
+			*field(c) = b
+			return nil
+		},
+	}
+}
+
+var settings = []setting{
+	stringSetting("data_file", "calendar data file", func(c *Config) *string { return &c.DataFile }),
+	stringSetting("history_file", "command history file", func(c *Config) *string { return &c.HistoryFile }),
+	stringSetting("log_file", "application log file", func(c *Config) *string { return &c.LogFile }),
+	stringSetting("log_level", "log level: debug, info, warn or error", func(c *Config) *string { return &c.LogLevel }),
+	stringSetting("log_format", "log format: text or json", func(c *Config) *string { return &c.LogFormat }),
+	intSetting("log_max_size", "size of the log file in MB before it is rotated, 0 disables", func(c *Config) *int { return &c.LogMaxSize }),
+	stringSetting("log_rotate_every", "how long one log file is written before it is rotated, e.g. 24h, 0 disables", func(c *Config) *string { return &c.LogRotateEvery }),
+	stringSetting("log_max_age", "rotated log files older than this are removed, e.g. 720h, 0 keeps them", func(c *Config) *string { return &c.LogMaxAge }),
+	intSetting("log_max_files", "number of rotated log files to keep, 0 keeps all", func(c *Config) *int { return &c.LogMaxFiles }),
+	boolSetting("log_compress", "compress rotated log files with gzip", func(c *Config) *bool { return &c.LogCompress }),
+	intSetting("history_max_size", "size of the command history file in MB before it is rotated, 0 disables", func(c *Config) *int { return &c.HistoryMaxSize }),
+	intSetting("history_max_files", "number of rotated command history files to keep, 0 keeps all", func(c *Config) *int { return &c.HistoryFiles }),
+	stringSetting("undo_file", "undo/redo log file", func(c *Config) *string { return &c.UndoFile }),
+	stringSetting("audit_file", "audit trail of event changes, relative to the data directory", func(c *Config) *string { return &c.AuditFile }),
+	stringSetting("actor", "name recorded in the audit trail, the OS user name by default", func(c *Config) *string { return &c.Actor }),
+	intSetting("undo_depth", "number of operations that can be undone, 0 disables undo", func(c *Config) *int { return &c.UndoDepth }),
+	stringSetting("storage", "storage backend: json, zip, sqlite, journal or git", func(c *Config) *string { return &c.Storage }),
+	stringSetting("compression", "zip storage compression: deflate, zstd or gzip", func(c *Config) *string { return &c.Compression }),
+	intSetting("compact_every", "journal records before it is compacted into a snapshot", func(c *Config) *int { return &c.CompactEvery }),
+	stringSetting("encryption", "encryption at rest: none, passphrase or keyfile", func(c *Config) *string { return &c.Encryption }),
+	stringSetting("key_file", "key file for keyfile encryption, relative to the config directory", func(c *Config) *string { return &c.KeyFile }),
+	stringSetting("backup", "calendar snapshots: off, schedule (every backup_interval) or change (after every change)", func(c *Config) *string { return &c.Backup }),
+	stringSetting("backup_interval", "interval between scheduled snapshots, e.g. 1h or 30m", func(c *Config) *string { return &c.BackupInterval }),
+	stringSetting("backup_dir", "snapshot directory, relative to the data directory", func(c *Config) *string { return &c.BackupDir }),
+	intSetting("backup_last", "number of most recent snapshots to keep", func(c *Config) *int { return &c.BackupLast }),
+	intSetting("backup_hourly", "number of hourly snapshots to keep", func(c *Config) *int { return &c.BackupHourly }),
+	intSetting("backup_daily", "number of daily snapshots to keep", func(c *Config) *int { return &c.BackupDaily }),
+	intSetting("backup_weekly", "number of weekly snapshots to keep", func(c *Config) *int { return &c.BackupWeekly }),
+	stringSetting("time_zone", "IANA time zone used to enter and display dates", func(c *Config) *string { return &c.TimeZone }),
+	stringSetting("date_format", "Go layout used to enter and display dates", func(c *Config) *string { return &c.DateFormat }),
+	stringSetting("first_weekday", "first day of the week: mon, sun or sat", func(c *Config) *string { return &c.FirstWeekday }),
+	{
+		key:   "zones",
+		usage: "comma-separated list of additional time zones",
+		get:   func(c *Config) string { return strings.Join(c.Zones, ",") },
+		set: func(c *Config, value string) error {
+			c.Zones = SplitList(value)
+			return nil
+		},
+	},
+	intSetting("work_hours_start", "start of working hours for the planner", func(c *Config) *int { return &c.WorkHoursStart }),
+	intSetting("work_hours_end", "end of working hours for the planner", func(c *Config) *int { return &c.WorkHoursEnd }),
+	{
+		key:   "tag_colors",
+		usage: "comma-separated tag colors such as work=blue,personal=green",
+		get:   func(c *Config) string { return c.TagColorList() },
+		set: func(c *Config, value string) error {
+			colors, err := tags.ParseColors(value)
+			if err != nil {
+				return err
+			}
+			c.TagColors = colors
+			return nil
+		},
+	},
+}
@@ -0,0 +1,289 @@
+package dateparse
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrEmpty = errors.New("date string is empty")
+var ErrAmbiguous = errors.New("ambiguous date")
+var ErrUnrecognized = errors.New("unrecognized date")
+
+const DefaultHour = 9
+
+const Examples = `"2025-12-25 18:00", "tomorrow 15:00", "завтра 15:00", "next monday 9am", "в пт 10:30", "in 2h", "через 30 минут", "fri", "25.12 18:00"`
+
+var weekdays = map[string]time.Weekday{
+	"mon": time.Monday, "monday": time.Monday, "пн": time.Monday, "понедельник": time.Monday,
+	"tue": time.Tuesday, "tues": time.Tuesday, "tuesday": time.Tuesday, "вт": time.Tuesday, "вторник": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday, "ср": time.Wednesday, "среда": time.Wednesday, "среду": time.Wednesday,
+	"thu": time.Thursday, "thurs": time.Thursday, "thursday": time.Thursday, "чт": time.Thursday, "четверг": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday, "пт": time.Friday, "пятница": time.Friday, "пятницу": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday, "сб": time.Saturday, "суббота": time.Saturday, "субботу": time.Saturday,
+	"sun": time.Sunday, "sunday": time.Sunday, "вс": time.Sunday, "воскресенье": time.Sunday,
+}
+
+var relativeDays = map[string]int{
+	"yesterday": -1, "вчера": -1,
+	"today": 0, "сегодня": 0,
+	"tomorrow": 1, "завтра": 1,
+	"послезавтра": 2,
+}
+
+var nextWords = map[string]bool{
+	"next": true, "следующий": true, "следующая": true, "следующее": true, "следующую": true, "след": true,
+}
+
+var fillerWords = map[string]bool{
+	"at": true, "on": true, "this": true, "в": true, "во": true, "к": true,
+	"этот": true, "эта": true, "это": true, "эту": true,
+}
+
+var (
+	isoTimeRe   = regexp.MustCompile(`(\d{4}-\d{1,2}-\d{1,2})t(\d)`)
+	isoDateRe   = regexp.MustCompile(`^(\d{4})-(\d{1,2})-(\d{1,2})$`)
+	dotDateRe   = regexp.MustCompile(`^(\d{1,2})\.(\d{1,2})(?:\.(\d{2}|\d{4}))?$`)
+	slashDateRe = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})(?:/(\d{2}|\d{4}))?$`)
+	clockRe     = regexp.MustCompile(`^(\d{1,2}):(\d{2})(am|pm)?$`)
+	meridiemRe  = regexp.MustCompile(`^(\d{1,2})(am|pm)$`)
+	numberRe    = regexp.MustCompile(`^\d{1,2}$`)
+	durationRe  = regexp.MustCompile(`^(\d+)?([a-zа-я]+)$`)
+)
+
+type result struct {
+	now     time.Time
+	date    *time.Time
+	hour    int
+	minute  int
+	hasTime bool
+	next    bool
+}
+
+// Parse разбирает абсолютные, относительные и словесные даты (на русском и английском)
+// относительно now; результат возвращается в зоне now.
+func Parse(input string, now time.Time) (time.Time, error) {
+	s := strings.ToLower(strings.TrimSpace(input))
+	if s == "" {
+		return time.Time{}, ErrEmpty
+	}
+
+	if t, ok, err := parseRelative(s, now); ok {
+		return t, err
+	}
+
+	s = isoTimeRe.ReplaceAllString(strings.ReplaceAll(s, ",", " "), "$1 $2")
+	r := &result{now: now}
+	tokens := strings.Fields(s)
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if i+1 < len(tokens) && numberRe.MatchString(tok) {
+			if m := meridiem(tokens[i+1]); m != "" {
+				if err := r.setClock(tok, "00", m, input); err != nil {
+					return time.Time{}, err
+				}
+				i++
+				continue
+			}
+		}
+		if err := r.consume(tok, input); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	if r.date == nil && !r.hasTime {
+		return time.Time{}, fmt.Errorf("%w: %q", ErrUnrecognized, input)
+	}
+	if r.next && r.date == nil {
+		return time.Time{}, fmt.Errorf("%w: %q: \"next\" must be followed by a weekday", ErrUnrecognized, input)
+	}
+
+	date := startOfDay(now)
+	if r.date != nil {
+		date = *r.date
+	}
+	if !r.hasTime {
+		r.hour = DefaultHour
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), r.hour, r.minute, 0, 0, now.Location()), nil
+}
+
+func (r *result) consume(tok, input string) error {
+	switch {
+	case fillerWords[tok]:
+		return nil
+	case nextWords[tok]:
+		r.next = true
+		return nil
+	}
+
+	if days, ok := relativeDays[tok]; ok {
+		return r.setDate(startOfDay(r.now).AddDate(0, 0, days), input)
+	}
+
+	if wd, ok := weekdays[tok]; ok {
+		today := startOfDay(r.now)
+		offset := (int(wd) - int(today.Weekday()) + 7) % 7
+		if offset == 0 && r.next {
+			offset = 7
+		}
+		return r.setDate(today.AddDate(0, 0, offset), input)
+	}
+
+	if m := isoDateRe.FindStringSubmatch(tok); m != nil {
+		return r.setDay(atoi(m[1]), atoi(m[2]), atoi(m[3]), input)
+	}
+
+	if m := dotDateRe.FindStringSubmatch(tok); m != nil {
+		return r.setDayMonth(atoi(m[1]), atoi(m[2]), m[3], input)
+	}
+
+	if m := slashDateRe.FindStringSubmatch(tok); m != nil {
+		first, second := atoi(m[1]), atoi(m[2])
+		if first <= 12 && second <= 12 && first != second {
+			return fmt.Errorf("%w: %q can be read as day/month or month/day, use \"%02d.%02d\" or \"YYYY-MM-DD\"", ErrAmbiguous, tok, first, second)
+		}
+		if second > 12 {
+			first, second = second, first
+		}
+		return r.setDayMonth(first, second, m[3], input)
+	}
+
+	if m := clockRe.FindStringSubmatch(tok); m != nil {
+		return r.setClock(m[1], m[2], m[3], input)
+	}
+
+	if m := meridiemRe.FindStringSubmatch(tok); m != nil {
+		return r.setClock(m[1], "00", m[2], input)
+	}
+
+	if numberRe.MatchString(tok) {
+		return fmt.Errorf("%w: hour %q is ambiguous, use \"%s:00\", \"%sam\" or \"%spm\"", ErrAmbiguous, tok, tok, tok, tok)
+	}
+
+	return fmt.Errorf("%w: unknown word %q in %q", ErrUnrecognized, tok, input)
+}
+
+func (r *result) setDate(d time.Time, input string) error {
+	if r.date != nil {
+		return fmt.Errorf("%w: %q contains more than one date", ErrAmbiguous, input)
+	}
+	r.date = &d
+	return nil
+}
+
+func (r *result) setDay(year, month, day int, input string) error {
+	d := time.Date(year, time.Month(month), day, 0, 0, 0, 0, r.now.Location())
+	if d.Year() != year || int(d.Month()) != month || d.Day() != day {
+		return fmt.Errorf("%w: date %04d-%02d-%02d does not exist", ErrUnrecognized, year, month, day)
+	}
+	return r.setDate(d, input)
+}
+
+func (r *result) setDayMonth(day, month int, yearStr string, input string) error {
+	if yearStr != "" {
+		year := atoi(yearStr)
+		if len(yearStr) == 2 {
+			year += 2000
+		}
+		return r.setDay(year, month, day, input)
+	}
+
+	year := r.now.Year()
+	d := time.Date(year, time.Month(month), day, 0, 0, 0, 0, r.now.Location())
+	if d.Before(startOfDay(r.now)) {
+		year++
+	}
+	return r.setDay(year, month, day, input)
+}
+
+func (r *result) setClock(hourStr, minuteStr, mer string, input string) error {
+	if r.hasTime {
+		return fmt.Errorf("%w: %q contains more than one time", ErrAmbiguous, input)
+	}
+	hour, minute := atoi(hourStr), atoi(minuteStr)
+	if minute > 59 {
+		return fmt.Errorf("%w: invalid minutes in %q", ErrUnrecognized, input)
+	}
+	switch mer {
+	case "am", "pm":
+		if hour < 1 || hour > 12 {
+			return fmt.Errorf("%w: invalid hour %d%s", ErrUnrecognized, hour, mer)
+		}
+		hour %= 12
+		if mer == "pm" {
+			hour += 12
+		}
+	default:
+		if hour > 23 {
+			return fmt.Errorf("%w: invalid hour %d", ErrUnrecognized, hour)
+		}
+	}
+	r.hour, r.minute, r.hasTime = hour, minute, true
+	return nil
+}
+
+func meridiem(tok string) string {
+	switch tok {
+	case "am", "утра", "ночи":
+		return "am"
+	case "pm", "вечера", "дня":
+		return "pm"
+	}
+	return ""
+}
+
+func parseRelative(s string, now time.Time) (time.Time, bool, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 || (fields[0] != "in" && fields[0] != "через") {
+		return time.Time{}, false, nil
+	}
+	rest := strings.Join(fields[1:], "")
+	m := durationRe.FindStringSubmatch(rest)
+	if m == nil {
+		return time.Time{}, true, fmt.Errorf("%w: can't read duration in %q", ErrUnrecognized, s)
+	}
+	n := 1
+	if m[1] != "" {
+		n = atoi(m[1])
+	}
+
+	switch unitOf(m[2]) {
+	case "minute":
+		return now.Add(time.Duration(n) * time.Minute), true, nil
+	case "hour":
+		return now.Add(time.Duration(n) * time.Hour), true, nil
+	case "day":
+		return now.AddDate(0, 0, n), true, nil
+	case "week":
+		return now.AddDate(0, 0, 7*n), true, nil
+	}
+	return time.Time{}, true, fmt.Errorf("%w: unknown unit %q in %q", ErrUnrecognized, m[2], s)
+}
+
+func unitOf(unit string) string {
+	switch {
+	case unit == "m" || unit == "м" || strings.HasPrefix(unit, "min") || strings.HasPrefix(unit, "мин"):
+		return "minute"
+	case unit == "h" || unit == "ч" || strings.HasPrefix(unit, "hour") || unit == "hr" || unit == "hrs" || strings.HasPrefix(unit, "час"):
+		return "hour"
+	case unit == "d" || unit == "д" || strings.HasPrefix(unit, "day") || unit == "день" || unit == "дня" || unit == "дней":
+		return "day"
+	case unit == "w" || strings.HasPrefix(unit, "week") || strings.HasPrefix(unit, "нед"):
+		return "week"
+	}
+	return ""
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
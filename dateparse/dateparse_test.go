@@ -0,0 +1,64 @@
+package dateparse
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2025, 3, 5, 10, 0, 0, 0, time.FixedZone("IRKT", 8*3600))
+
+func TestParse_Valid(t *testing.T) {
+	loc := now.Location()
+	cases := map[string]time.Time{
+		"2025-03-10 15:04":     time.Date(2025, 3, 10, 15, 4, 0, 0, loc),
+		"2025-03-10T15:04":     time.Date(2025, 3, 10, 15, 4, 0, 0, loc),
+		"tomorrow 15:00":       time.Date(2025, 3, 6, 15, 0, 0, 0, loc),
+		"завтра 15:00":         time.Date(2025, 3, 6, 15, 0, 0, 0, loc),
+		"послезавтра в 8:30":   time.Date(2025, 3, 7, 8, 30, 0, 0, loc),
+		"next monday 9am":      time.Date(2025, 3, 10, 9, 0, 0, 0, loc),
+		"next wed 9pm":         time.Date(2025, 3, 12, 21, 0, 0, 0, loc),
+		"в следующую среду":    time.Date(2025, 3, 12, DefaultHour, 0, 0, 0, loc),
+		"fri":                  time.Date(2025, 3, 7, DefaultHour, 0, 0, 0, loc),
+		"пт 7 вечера":          time.Date(2025, 3, 7, 19, 0, 0, 0, loc),
+		"25.12 18:00":          time.Date(2025, 12, 25, 18, 0, 0, 0, loc),
+		"01.02 18:00":          time.Date(2026, 2, 1, 18, 0, 0, 0, loc),
+		"25.12.2026 18:00":     time.Date(2026, 12, 25, 18, 0, 0, 0, loc),
+		"25/12 18:00":          time.Date(2025, 12, 25, 18, 0, 0, 0, loc),
+		"in 2h":                now.Add(2 * time.Hour),
+		"через 30 минут":       now.Add(30 * time.Minute),
+		"через час":            now.Add(time.Hour),
+		"in 3 days":            now.AddDate(0, 0, 3),
+		"18:30":                time.Date(2025, 3, 5, 18, 30, 0, 0, loc),
+		"today at 12:15pm":     time.Date(2025, 3, 5, 12, 15, 0, 0, loc),
+		"сегодня, 12 ночи":     time.Date(2025, 3, 5, 0, 0, 0, 0, loc),
+		"  Tomorrow   15:00  ": time.Date(2025, 3, 6, 15, 0, 0, 0, loc),
+	}
+	for input, expected := range cases {
+		actual, err := Parse(input, now)
+		if err != nil {
+			t.Errorf("Parse(%q): unexpected error: %v", input, err)
+			continue
+		}
+		if !actual.Equal(expected) {
+			t.Errorf("Parse(%q) = %v, expected %v", input, actual, expected)
+		}
+	}
+}
+
+func TestParse_Ambiguous(t *testing.T) {
+	for _, input := range []string{"05/03 10:00", "tomorrow 9", "завтра пт", "15:00 16:00"} {
+		_, err := Parse(input, now)
+		if !errors.Is(err, ErrAmbiguous) {
+			t.Errorf("Parse(%q): expected ErrAmbiguous, got %v", input, err)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, input := range []string{"", "2024-02-30 10:30", "27-10-2024 10:30", "31.02 10:00", "25:00", "13pm", "in 2 parsecs", "next", "someday"} {
+		if _, err := Parse(input, now); err == nil {
+			t.Errorf("Parse(%q): expected an error, got none", input)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package events
+
+import (
+	"strings"
+	"time"
+)
+
+// Change — изменение одного поля события.
+type Change struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// Diff сравнивает два состояния события по полям; nil означает, что события не было или оно удалено.
+func Diff(before, after *Event) []Change {
+	var changes []Change
+	b, a := fields(before), fields(after)
+	for i := range a {
+		if b[i].value != a[i].value {
+			changes = append(changes, Change{Field: a[i].name, Before: b[i].value, After: a[i].value})
+		}
+	}
+	return changes
+}
+
+type field struct {
+	name  string
+	value string
+}
+
+func fields(e *Event) []field {
+	if e == nil {
+		e = &Event{}
+	}
+	start := ""
+	if !e.StartAt.IsZero() {
+		start = e.StartAt.In(e.Location()).Format(time.RFC3339)
+	}
+	reminder := ""
+	if r := e.Reminder; r != nil {
+		reminder = r.Message + " @ " + r.At.In(ViewerLocation()).Format(DateLayout())
+		if r.Sent {
+			reminder += " (отправлено)"
+		}
+	}
+	return []field{
+		{"title", e.Title},
+		{"start_at", start},
+		{"zone", e.Zone},
+		{"priority", string(e.Priority)},
+		{"reminder", reminder},
+		{"tags", strings.Join(e.Tags, ",")},
+		{"description", e.Description},
+		{"location", e.Place},
+		{"url", e.URL},
+		{"attendees", strings.Join(e.Attendees, ", ")},
+	}
+}
@@ -3,52 +3,75 @@ package events
 import (
 	"errors"
 	"fmt"
+	"github.com/TsSol87/calendarApp/dateparse"
 	"github.com/TsSol87/calendarApp/priority"
 	"github.com/TsSol87/calendarApp/reminder"
+	"github.com/TsSol87/calendarApp/tags"
 	"github.com/google/uuid"
-	"regexp"
+	"slices"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
-var ErrIsValidTitle = errors.New("Title does not match the required pattern")
+var ErrIsValidTitle = errors.New("Title must be 3 to 50 printable characters")
 var ErrIsValidDate = errors.New("Invalid date format")
 
 const TimeZone = "Asia/Irkutsk"
 const DateFormat = "2006-01-02 15:04"
 
 type Event struct {
-	ID       string             `json:"id"`
-	Title    string             `json:"title"`
-	StartAt  time.Time          `json:"start_at"`
-	Priority priority.Priority  `json:"priority"`
-	Reminder *reminder.Reminder `json:"reminder"`
+	ID          string             `json:"id"`
+	Title       string             `json:"title"`
+	StartAt     time.Time          `json:"start_at"`
+	Zone        string             `json:"zone,omitempty"`
+	Priority    priority.Priority  `json:"priority"`
+	Reminder    *reminder.Reminder `json:"reminder"`
+	Tags        []string           `json:"tags,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Place       string             `json:"location,omitempty"` // место проведения; имя Location занято часовым поясом
+	URL         string             `json:"url,omitempty"`
+	Attendees   []string           `json:"attendees,omitempty"`
 }
 
 func getNextID() string {
 	return uuid.New().String()
 }
 
+// Границы длины названия в символах, без пробелов по краям.
+const (
+	minTitleLength = 3
+	maxTitleLength = 50
+)
+
+// IsValidTitle принимает названия из любых печатаемых символов, например "Call: Acme, Inc." или "Встреча №5".
+// Пробелы по краям не считаются: NewEvent, Update и SetField их обрезают.
 func IsValidTitle(title string) error {
-	pattern := "^[a-zA-Z0-9а-яА-Я ]{3,50}$"
-	matched, err := regexp.MatchString(pattern, title)
-	if err != nil {
-		return fmt.Errorf("regexp error: %w", err)
-	}
-	if !matched {
+	title = strings.TrimSpace(title)
+	if n := utf8.RuneCountInString(title); n < minTitleLength || n > maxTitleLength {
 		return ErrIsValidTitle
 	}
+	for _, r := range title {
+		if !unicode.IsPrint(r) {
+			return ErrIsValidTitle
+		}
+	}
 	return nil
 }
 
 func TimeParse(dataStr string) (time.Time, error) {
-	location, err := time.LoadLocation(TimeZone)
+	dataStr, location, err := splitZone(dataStr)
 	if err != nil {
-		return time.Time{}, fmt.Errorf("failed to load time zone '%s': %w", TimeZone, err)
+		return time.Time{}, err
 	}
 
-	at, err := time.ParseInLocation(DateFormat, dataStr, location)
+	if at, err := time.ParseInLocation(DateLayout(), dataStr, location); err == nil {
+		return at, nil
+	}
+	at, err := dateparse.Parse(dataStr, time.Now().In(location))
 	if err != nil {
-		return time.Time{}, fmt.Errorf("failed to parse date string '%s' with format '%s'", dataStr, DateFormat)
+		return time.Time{}, fmt.Errorf("failed to parse date string '%s': %w", dataStr, err)
 	}
 
 	return at, nil
@@ -63,7 +86,7 @@ func NewEvent(title string, dateStr string, priorityStr string) (*Event, error)
 
 	t, errTimeParse := TimeParse(dateStr)
 	if errTimeParse != nil {
-		return nil, fmt.Errorf("can't create date: %w", ErrIsValidDate)
+		return nil, fmt.Errorf("can't create date: %w: %w", ErrIsValidDate, errTimeParse)
 	}
 
 	p := priority.Priority(priorityStr)
@@ -73,8 +96,9 @@ func NewEvent(title string, dateStr string, priorityStr string) (*Event, error)
 
 	return &Event{
 		ID:       getNextID(),
-		Title:    title,
-		StartAt:  t,
+		Title:    strings.TrimSpace(title),
+		StartAt:  t.UTC(),
+		Zone:     t.Location().String(),
 		Priority: p,
 		Reminder: nil,
 	}, nil
@@ -89,7 +113,7 @@ func (e *Event) Update(title string, dateStr string, priorityStr string) error {
 
 	time, errTimeParse := TimeParse(dateStr)
 	if errTimeParse != nil {
-		return fmt.Errorf("can't create date: %w", errTimeParse)
+		return fmt.Errorf("can't create date: %w: %w", ErrIsValidDate, errTimeParse)
 	}
 
 	p := priority.Priority(priorityStr)
@@ -97,19 +121,92 @@ func (e *Event) Update(title string, dateStr string, priorityStr string) error {
 		return err
 	}
 
-	e.Title = title
-	e.StartAt = time
+	e.Title = strings.TrimSpace(title)
+	e.StartAt = time.UTC()
+	e.Zone = time.Location().String()
 	e.Priority = p
 	return nil
 }
 
 func (e Event) Print() {
-	fmt.Printf("ID: %s  Событие: %s  Дата: %s  Приоритет: %s (Напоминание: %s)\n", e.ID, e.Title, e.StartAt.Format("2006-01-02T15:04:05"), e.Priority, e.Reminder)
+	loc := ViewerLocation()
+	date := e.StartAt.In(loc).Format(DateLayout())
+	if e.Zone != "" && e.Zone != loc.String() {
+		date += fmt.Sprintf(" (%s %s)", e.StartAt.In(e.Location()).Format("15:04"), e.Zone)
+	}
+	labels := ""
+	if len(e.Tags) > 0 {
+		labels = "  Метки: " + tags.Format(e.Tags)
+	}
+	fmt.Printf("ID: %s  Событие: %s  Дата: %s  Приоритет: %s (Напоминание: %s)%s\n", e.ID, e.Title, date, e.Priority, e.Reminder.Format(loc), labels)
+}
+
+// AddTags добавляет метки, которых у события ещё нет, и возвращает добавленные.
+func (e *Event) AddTags(list ...string) ([]string, error) {
+	parsed, err := tags.Parse(list...)
+	if err != nil {
+		return nil, err
+	}
+	var added []string
+	for _, tag := range parsed {
+		if !slices.Contains(e.Tags, tag) {
+			e.Tags = append(e.Tags, tag)
+			added = append(added, tag)
+		}
+	}
+	slices.Sort(e.Tags)
+	return added, nil
+}
+
+// RemoveTags снимает метки с события и возвращает снятые.
+func (e *Event) RemoveTags(list ...string) ([]string, error) {
+	parsed, err := tags.Parse(list...)
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for _, tag := range parsed {
+		if i := slices.Index(e.Tags, tag); i >= 0 {
+			e.Tags = slices.Delete(e.Tags, i, i+1)
+			removed = append(removed, tag)
+		}
+	}
+	if len(e.Tags) == 0 {
+		e.Tags = nil
+	}
+	return removed, nil
+}
+
+// HasAnyTag сообщает, есть ли у события хотя бы одна из меток; пустой список подходит любому событию.
+func (e *Event) HasAnyTag(list []string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, tag := range list {
+		if slices.Contains(e.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterTags оставляет события, у которых есть хотя бы одна из меток.
+func FilterTags(list []*Event, tags []string) []*Event {
+	if len(tags) == 0 {
+		return list
+	}
+	var result []*Event
+	for _, e := range list {
+		if e.HasAnyTag(tags) {
+			result = append(result, e)
+		}
+	}
+	return result
 }
 
 func (e *Event) AddReminder(message string, at time.Time, notify func(msg string)) error {
 	var err error
-	e.Reminder, err = reminder.NewReminder(message, at, notify)
+	e.Reminder, err = reminder.NewReminder(message, at.UTC(), notify)
 	if err != nil {
 		return err
 	}
@@ -117,6 +214,12 @@ func (e *Event) AddReminder(message string, at time.Time, notify func(msg string
 	return nil
 }
 
+func (e *Event) ResumeReminder(notify func(msg string)) {
+	if e.Reminder != nil {
+		e.Reminder.Resume(notify)
+	}
+}
+
 func (e *Event) RemoveReminder() {
 	if e.Reminder != nil {
 		e.Reminder.Stop()
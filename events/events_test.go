@@ -1,7 +1,10 @@
 package events
 
 import (
+	"errors"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestIsValidTitle(t *testing.T) {
@@ -29,10 +32,40 @@ func TestIsValidTitle_Long(t *testing.T) {
 }
 
 func TestIsValidTitle_InvalidCharacters(t *testing.T) {
-	text := "Invalid character!"
-	err := IsValidTitle(text)
-	if err == nil {
-		t.Errorf("Expected an error for string with invalid characters, got none")
+	for _, text := range []string{"Line\nbreak", "Tab\tinside", "Bell\a"} {
+		if err := IsValidTitle(text); !errors.Is(err, ErrIsValidTitle) {
+			t.Errorf("Expected an error for %q with a control character, got %v", text, err)
+		}
+	}
+}
+
+func TestIsValidTitle_Punctuation(t *testing.T) {
+	for _, text := range []string{"Call: Acme, Inc.", "Встреча №5", "Q&A (2025) — итоги!", "Обед 🍕"} {
+		if err := IsValidTitle(text); err != nil {
+			t.Errorf("Expected no error for %q, got %v", text, err)
+		}
+	}
+}
+
+func TestIsValidTitle_CountsRunesWithoutEdgeSpaces(t *testing.T) {
+	if err := IsValidTitle("  Hi  "); err == nil {
+		t.Error("Expected edge spaces not to count towards the length")
+	}
+	if err := IsValidTitle(strings.Repeat("я", 50)); err != nil {
+		t.Errorf("Expected 50 Cyrillic letters to fit, got %v", err)
+	}
+	if err := IsValidTitle(strings.Repeat("я", 51)); err == nil {
+		t.Error("Expected 51 letters to be too long")
+	}
+}
+
+func TestNewEvent_TrimsTitle(t *testing.T) {
+	e, err := NewEvent("  Call: Acme, Inc.  ", "2030-01-01 10:00", "high")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Title != "Call: Acme, Inc." {
+		t.Errorf("Expected the title to be trimmed, got %q", e.Title)
 	}
 }
 
@@ -55,8 +88,8 @@ func TestIsValidTitle_OnlyNumbers(t *testing.T) {
 func TestIsValidTitle_OnlySpaces(t *testing.T) {
 	text := "   "
 	err := IsValidTitle(text)
-	if err != nil {
-		t.Errorf("Expected no error for string containing only spaces, got an error")
+	if err == nil {
+		t.Errorf("Expected an error for string containing only spaces, got none")
 	}
 }
 
@@ -105,3 +138,164 @@ func TestTimeParse_ValidFormat(t *testing.T) {
 		t.Errorf("Expected no error for valid date format, but got error")
 	}
 }
+
+func TestTimeParse_ExplicitZone(t *testing.T) {
+	at, err := TimeParse("2024-10-27 10:30 Europe/Berlin")
+	if err != nil {
+		t.Fatalf("Expected no error for date with explicit zone, but got: %v", err)
+	}
+	if at.Location().String() != "Europe/Berlin" {
+		t.Errorf("Expected zone Europe/Berlin, got %s", at.Location())
+	}
+	if at.UTC().Hour() != 9 {
+		t.Errorf("Expected 09:30 UTC (CET after DST end), got %s", at.UTC())
+	}
+}
+
+func TestTimeParse_UnknownZone(t *testing.T) {
+	_, err := TimeParse("2024-10-27 10:30 Mars/Olympus")
+	if err == nil {
+		t.Errorf("Expected an error for unknown time zone, but got none")
+	}
+}
+
+func TestNewEvent_StoresUTCWithZone(t *testing.T) {
+	e, err := NewEvent("Meeting", "2024-07-01 10:00 America/New_York", "high")
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if e.StartAt.Location() != time.UTC {
+		t.Errorf("Expected StartAt in UTC, got %s", e.StartAt.Location())
+	}
+	if e.Zone != "America/New_York" || e.StartAt.Hour() != 14 {
+		t.Errorf("Expected 14:00 UTC with zone America/New_York, got %s %s", e.StartAt, e.Zone)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	before, err := NewEvent("Meeting", "2030-01-01 10:00", "high")
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := *before
+	after.Title = "Renamed"
+	after.Priority = "low"
+
+	changes := Diff(before, &after)
+	if len(changes) != 2 || changes[0].Field != "title" || changes[0].Before != "Meeting" || changes[0].After != "Renamed" || changes[1].Field != "priority" {
+		t.Errorf("Expected title and priority changes, got %+v", changes)
+	}
+	if len(Diff(before, before)) != 0 {
+		t.Error("Expected no changes for the same event")
+	}
+	if changes := Diff(nil, before); len(changes) != 4 {
+		t.Errorf("Expected every set field of a new event to be reported, got %+v", changes)
+	}
+}
+
+func TestEvent_Tags(t *testing.T) {
+	e, err := NewEvent("Meeting", "2030-01-01 10:00", "high")
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := *e
+	added, err := e.AddTags("Work", "#travel,work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != 2 || len(e.Tags) != 2 || e.Tags[0] != "travel" || e.Tags[1] != "work" {
+		t.Errorf("Expected sorted tags without duplicates, got added %v, tags %v", added, e.Tags)
+	}
+	if !e.HasAnyTag([]string{"personal", "work"}) || e.HasAnyTag([]string{"personal"}) {
+		t.Errorf("Unexpected tag match for %v", e.Tags)
+	}
+	if changes := Diff(&before, e); len(changes) != 1 || changes[0].Field != "tags" || changes[0].After != "travel,work" {
+		t.Errorf("Expected the tags change, got %+v", changes)
+	}
+
+	removed, err := e.RemoveTags("work", "personal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || len(e.Tags) != 1 || e.Tags[0] != "travel" {
+		t.Errorf("Expected only work to be removed, got removed %v, tags %v", removed, e.Tags)
+	}
+	if _, err := e.AddTags("bad tag"); err == nil {
+		t.Error("Expected an error for an invalid tag")
+	}
+}
+
+func TestEvent_SetField(t *testing.T) {
+	e, err := NewEvent("Meeting", "2030-01-01 10:00", "high")
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := *e
+	for field, value := range map[string]string{
+		FieldDescription: "Agenda:\r\n1. Budget\n2. Plans",
+		FieldLocation:    "Room 4, 2nd floor",
+		FieldURL:         "https://meet.example.com/abc?x=1",
+		FieldAttendees:   "Anna, bob@example.com, Anna",
+	} {
+		if err := e.SetField(field, value); err != nil {
+			t.Fatalf("SetField(%s): %v", field, err)
+		}
+	}
+	if e.Description != "Agenda:\n1. Budget\n2. Plans" || e.Place != "Room 4, 2nd floor" || len(e.Attendees) != 2 {
+		t.Errorf("Unexpected fields %+v", e)
+	}
+	if e.Title != before.Title || !e.StartAt.Equal(before.StartAt) || e.Priority != before.Priority {
+		t.Errorf("Expected other fields to stay unchanged, got %+v", e)
+	}
+	if changes := Diff(&before, e); len(changes) != 4 || changes[0].Field != "description" {
+		t.Errorf("Expected four changed fields, got %+v", changes)
+	}
+
+	for field, value := range map[string]string{
+		FieldURL:      "ftp://example.com",
+		FieldLocation: "two\nlines",
+		FieldTitle:    "No",
+		FieldPriority: "urgent",
+	} {
+		if err := e.SetField(field, value); err == nil {
+			t.Errorf("Expected an error for %s %q", field, value)
+		}
+	}
+	if err := e.SetField("colour", "red"); !errors.Is(err, ErrUnknownField) {
+		t.Errorf("Expected ErrUnknownField, got %v", err)
+	}
+	if err := e.SetField(FieldURL, ""); err != nil || e.URL != "" {
+		t.Errorf("Expected an empty value to clear the url, got %q (%v)", e.URL, err)
+	}
+}
+
+func TestEvent_Patch(t *testing.T) {
+	e, err := NewEvent("Meeting", "2030-01-01 10:00", "high")
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := e.Patch(Patch{FieldPriority: "low", FieldTitle: "Meeting", FieldLocation: "Room 4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].Field != "priority" || changes[0].Before != "high" || changes[1].Field != "location" {
+		t.Errorf("Expected only priority and location to be reported, got %+v", changes)
+	}
+	if e.Title != "Meeting" || e.Priority != "low" || e.Place != "Room 4" {
+		t.Errorf("Unexpected event after patch: %+v", e)
+	}
+
+	before := *e
+	if _, err := e.Patch(Patch{FieldTitle: "Renamed", FieldDate: "not a date"}); !errors.Is(err, ErrIsValidDate) {
+		t.Errorf("Expected ErrIsValidDate, got %v", err)
+	}
+	if e.Title != before.Title {
+		t.Errorf("Expected an invalid patch to leave the event unchanged, got title %q", e.Title)
+	}
+	if _, err := e.Patch(Patch{"colour": "red"}); !errors.Is(err, ErrUnknownField) {
+		t.Errorf("Expected ErrUnknownField, got %v", err)
+	}
+	if changes, err := e.Patch(Patch{}); err != nil || len(changes) != 0 {
+		t.Errorf("Expected an empty patch to change nothing, got %+v (%v)", changes, err)
+	}
+}
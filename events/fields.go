@@ -0,0 +1,176 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/TsSol87/calendarApp/priority"
+)
+
+var ErrIsValidField = errors.New("Invalid field value")
+var ErrUnknownField = errors.New("unknown event field")
+
+// Поля события, которые можно менять по отдельности.
+const (
+	FieldTitle       = "title"
+	FieldDate        = "date"
+	FieldPriority    = "priority"
+	FieldDescription = "description"
+	FieldLocation    = "location"
+	FieldURL         = "url"
+	FieldAttendees   = "attendees"
+)
+
+var Fields = []string{FieldTitle, FieldDate, FieldPriority, FieldDescription, FieldLocation, FieldURL, FieldAttendees}
+
+const (
+	maxDescription = 2000
+	maxLocation    = 200
+	maxURL         = 500
+	maxAttendee    = 100
+)
+
+// SetField меняет одно поле события. Пустое значение очищает описание, место, ссылку и участников;
+// название, дата и приоритет проверяются так же, как в Update.
+func (e *Event) SetField(name, value string) error {
+	switch name {
+	case FieldTitle:
+		if err := IsValidTitle(value); err != nil {
+			return fmt.Errorf("can't change title: %w", err)
+		}
+		e.Title = strings.TrimSpace(value)
+	case FieldDate:
+		at, err := TimeParse(value)
+		if err != nil {
+			return fmt.Errorf("can't create date: %w: %w", ErrIsValidDate, err)
+		}
+		e.StartAt = at.UTC()
+		e.Zone = at.Location().String()
+	case FieldPriority:
+		p := priority.Priority(value)
+		if err := p.Validate(); err != nil {
+			return err
+		}
+		e.Priority = p
+	case FieldDescription:
+		value = strings.TrimSpace(strings.ReplaceAll(value, "\r\n", "\n"))
+		if err := checkLength(name, value, maxDescription); err != nil {
+			return err
+		}
+		e.Description = value
+	case FieldLocation:
+		value = strings.TrimSpace(value)
+		if err := checkLine(name, value, maxLocation); err != nil {
+			return err
+		}
+		e.Place = value
+	case FieldURL:
+		value = strings.TrimSpace(value)
+		if err := checkURL(value); err != nil {
+			return err
+		}
+		e.URL = value
+	case FieldAttendees:
+		list, err := ParseAttendees(value)
+		if err != nil {
+			return err
+		}
+		e.Attendees = list
+	default:
+		return fmt.Errorf("%w %q (use %s)", ErrUnknownField, name, strings.Join(Fields, ", "))
+	}
+	return nil
+}
+
+// Patch — новые значения полей события по их именам из Fields; поля, которых нет в Patch, не меняются.
+type Patch map[string]string
+
+// Patch меняет только переданные поля и возвращает изменения в порядке полей Diff.
+// Если хотя бы одно значение неверно, событие остаётся прежним.
+func (e *Event) Patch(p Patch) ([]Change, error) {
+	for name := range p {
+		if !slices.Contains(Fields, name) {
+			return nil, fmt.Errorf("%w %q (use %s)", ErrUnknownField, name, strings.Join(Fields, ", "))
+		}
+	}
+	changed := *e
+	for _, name := range Fields {
+		if value, ok := p[name]; ok {
+			if err := changed.SetField(name, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	changes := Diff(e, &changed)
+	*e = changed
+	return changes, nil
+}
+
+// Field возвращает значение поля в том виде, в котором его принимает SetField.
+func (e *Event) Field(name string) (string, error) {
+	switch name {
+	case FieldTitle:
+		return e.Title, nil
+	case FieldDate:
+		return e.StartAt.In(e.Location()).Format(DateLayout()) + " " + e.Location().String(), nil
+	case FieldPriority:
+		return string(e.Priority), nil
+	case FieldDescription:
+		return e.Description, nil
+	case FieldLocation:
+		return e.Place, nil
+	case FieldURL:
+		return e.URL, nil
+	case FieldAttendees:
+		return strings.Join(e.Attendees, ", "), nil
+	}
+	return "", fmt.Errorf("%w %q (use %s)", ErrUnknownField, name, strings.Join(Fields, ", "))
+}
+
+// ParseAttendees разбирает участников через запятую, например "Анна, bob@example.com", без повторов.
+func ParseAttendees(value string) ([]string, error) {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" || slices.Contains(list, item) {
+			continue
+		}
+		if err := checkLine(FieldAttendees, item, maxAttendee); err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+	}
+	return list, nil
+}
+
+func checkLength(name, value string, limit int) error {
+	if n := utf8.RuneCountInString(value); n > limit {
+		return fmt.Errorf("%w: %s is %d characters long, at most %d allowed", ErrIsValidField, name, n, limit)
+	}
+	return nil
+}
+
+func checkLine(name, value string, limit int) error {
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("%w: %s must be a single line", ErrIsValidField, name)
+	}
+	return checkLength(name, value, limit)
+}
+
+func checkURL(value string) error {
+	if value == "" {
+		return nil
+	}
+	if err := checkLine(FieldURL, value, maxURL); err != nil {
+		return err
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url %q must be an http or https link", ErrIsValidField, value)
+	}
+	return nil
+}
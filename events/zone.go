@@ -0,0 +1,110 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	zoneMutex   sync.RWMutex
+	defaultZone = TimeZone
+	dateLayout  = DateFormat
+)
+
+func ValidateDateFormat(layout string) error {
+	sample := time.Date(2006, time.November, 25, 21, 47, 0, 0, time.UTC)
+	parsed, err := time.Parse(layout, sample.Format(layout))
+	if err != nil {
+		return fmt.Errorf("layout %q can't be parsed back: %w", layout, err)
+	}
+	if !parsed.Equal(sample) {
+		return fmt.Errorf("layout %q must contain year, month, day, hours and minutes", layout)
+	}
+	return nil
+}
+
+func SetDateFormat(layout string) error {
+	if err := ValidateDateFormat(layout); err != nil {
+		return err
+	}
+	zoneMutex.Lock()
+	dateLayout = layout
+	zoneMutex.Unlock()
+	return nil
+}
+
+func DateLayout() string {
+	zoneMutex.RLock()
+	defer zoneMutex.RUnlock()
+	return dateLayout
+}
+
+func SetDefaultZone(name string) error {
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("failed to load time zone '%s': %w", name, err)
+	}
+	zoneMutex.Lock()
+	defaultZone = name
+	zoneMutex.Unlock()
+	return nil
+}
+
+func DefaultZone() string {
+	zoneMutex.RLock()
+	defer zoneMutex.RUnlock()
+	return defaultZone
+}
+
+func Location() (*time.Location, error) {
+	zone := DefaultZone()
+	location, err := time.LoadLocation(zone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load time zone '%s': %w", zone, err)
+	}
+	return location, nil
+}
+
+func ViewerLocation() *time.Location {
+	location, err := Location()
+	if err != nil {
+		return time.Local
+	}
+	return location
+}
+
+func (e Event) Location() *time.Location {
+	if e.Zone == "" {
+		return ViewerLocation()
+	}
+	location, err := time.LoadLocation(e.Zone)
+	if err != nil {
+		return ViewerLocation()
+	}
+	return location
+}
+
+// splitZone отделяет явную IANA-зону в конце строки даты ("2025-03-10 15:00 Europe/Berlin").
+func splitZone(dateStr string) (string, *time.Location, error) {
+	fields := strings.Fields(dateStr)
+	if len(fields) > 1 {
+		last := fields[len(fields)-1]
+		if (strings.Contains(last, "/") && !slashDate(last)) || last == "UTC" {
+			location, err := time.LoadLocation(last)
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to load time zone '%s': %w", last, err)
+			}
+			return strings.Join(fields[:len(fields)-1], " "), location, nil
+		}
+	}
+	location, err := Location()
+	if err != nil {
+		return "", nil, err
+	}
+	return dateStr, location, nil
+}
+
+func slashDate(s string) bool {
+	return strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '/' }) < 0
+}
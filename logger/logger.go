@@ -1,40 +1,161 @@
 package logger
 
 import (
-	"log"
-	"os"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/TsSol87/calendarApp/rotate"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
 )
 
+var Formats = []string{FormatText, FormatJSON}
+
+// Options — уровень (debug, info, warn, error), формат (text, json) и ротация файла журнала.
+type Options struct {
+	Level  string
+	Format string
+	Rotate rotate.Options
+}
+
+// До Init записи отбрасываются: импорт пакета ничего не открывает и не пишет.
 var (
-	InfoLogger   *log.Logger
-	ErrorLogger  *log.Logger
-	SystemLogger *log.Logger
-	file         *os.File
+	mutex   sync.RWMutex
+	current slog.Handler = slog.DiscardHandler
+	level   slog.LevelVar
+	file    io.Closer
 )
 
-func init() {
-	filename := "app.log"
-	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+func ParseLevel(s string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q (use debug, info, warn or error)", s)
+	}
+	return l, nil
+}
+
+// NewHandler создаёт обработчик записей в w; уровень берётся из SetLevel.
+func NewHandler(w io.Writer, format string) (slog.Handler, error) {
+	opts := &slog.HandlerOptions{Level: &level, AddSource: true, ReplaceAttr: shortSource}
+	switch format {
+	case FormatText, "":
+		return slog.NewTextHandler(w, opts), nil
+	case FormatJSON:
+		return slog.NewJSONHandler(w, opts), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q (use %s)", format, strings.Join(Formats, " or "))
+	}
+}
+
+// shortSource оставляет от пути к исходнику только каталог пакета и файл.
+func shortSource(groups []string, a slog.Attr) slog.Attr {
+	if a.Key != slog.SourceKey || len(groups) > 0 {
+		return a
+	}
+	if src, ok := a.Value.Any().(*slog.Source); ok {
+		return slog.String(slog.SourceKey, fmt.Sprintf("%s:%d", filepath.Join(filepath.Base(filepath.Dir(src.File)), filepath.Base(src.File)), src.Line))
+	}
+	return a
+}
+
+// Init открывает файл журнала и направляет в него записи всех компонентов.
+// Файл ротируется по opt.Rotate: старые части сжимаются и удаляются по правилам хранения.
+func Init(filename string, opt Options) error {
+	l, err := ParseLevel(opt.Level)
+	if err != nil {
+		return err
+	}
+	f, err := rotate.Open(filename, opt.Rotate)
+	if err != nil {
+		return err
+	}
+	h, err := NewHandler(f, opt.Format)
 	if err != nil {
-		log.Fatalf("Failed to open log file: %v", err)
+		f.Close()
+		return err
 	}
-	InfoLogger = log.New(file, "INFO: ", log.Ldate|log.Ltime|log.Lshortfile)
-	ErrorLogger = log.New(file, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile)
-	SystemLogger = log.New(file, "SYSTEM: ", log.Ldate|log.Ltime|log.Lshortfile)
+	level.Set(l)
+	mutex.Lock()
+	defer mutex.Unlock()
+	if file != nil {
+		file.Close()
+	}
+	file = f
+	current = h
+	return nil
+}
+
+// SetHandler направляет записи в h, например в тестах.
+func SetHandler(h slog.Handler) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	current = h
+}
+
+// SetLevel меняет уровень журнала без перезапуска.
+func SetLevel(l slog.Level) {
+	level.Set(l)
 }
 
 func Close() {
+	mutex.Lock()
+	defer mutex.Unlock()
 	if file != nil {
 		file.Close()
+		file = nil
+	}
+	current = slog.DiscardHandler
+}
+
+// Component возвращает журнал компонента: каждая запись получает атрибут component.
+// Журнал можно создать заранее, в переменной пакета: записи попадут в обработчик, заданный позже через Init.
+func Component(name string) *slog.Logger {
+	return slog.New(dynamic{}).With("component", name)
+}
+
+// dynamic передаёт записи текущему обработчику, применяя к нему накопленные With и WithGroup.
+type dynamic struct {
+	ops []func(slog.Handler) slog.Handler
+}
+
+func (d dynamic) handler() slog.Handler {
+	mutex.RLock()
+	h := current
+	mutex.RUnlock()
+	for _, op := range d.ops {
+		h = op(h)
 	}
+	return h
+}
+
+func (d dynamic) Enabled(ctx context.Context, l slog.Level) bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return current.Enabled(ctx, l)
 }
 
-func Info(msg string) {
-	InfoLogger.Output(2, msg)
+func (d dynamic) Handle(ctx context.Context, r slog.Record) error {
+	return d.handler().Handle(ctx, r)
 }
-func Error(msg string) {
-	ErrorLogger.Output(2, msg)
+
+func (d dynamic) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return d.with(func(h slog.Handler) slog.Handler { return h.WithAttrs(attrs) })
 }
-func System(msg string) {
-	SystemLogger.Output(2, msg)
+
+func (d dynamic) WithGroup(name string) slog.Handler {
+	return d.with(func(h slog.Handler) slog.Handler { return h.WithGroup(name) })
+}
+
+func (d dynamic) with(op func(slog.Handler) slog.Handler) dynamic {
+	ops := make([]func(slog.Handler) slog.Handler, len(d.ops), len(d.ops)+1)
+	copy(ops, d.ops)
+	return dynamic{ops: append(ops, op)}
 }
@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestComponent_JSONWithLevel(t *testing.T) {
+	log := Component("test")
+
+	var buf bytes.Buffer
+	h, err := NewHandler(&buf, FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetHandler(h)
+	SetLevel(slog.LevelInfo)
+	defer Close()
+
+	log.Debug("hidden")
+	log.Info("event changed", "event_id", "abc")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected 1 record at info level, got %d: %q", len(lines), buf.String())
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["component"] != "test" || record["event_id"] != "abc" || record["msg"] != "event changed" {
+		t.Errorf("Unexpected record: %v", record)
+	}
+
+	buf.Reset()
+	SetLevel(slog.LevelDebug)
+	log.Debug("shown")
+	if !strings.Contains(buf.String(), "shown") {
+		t.Errorf("Expected debug record after SetLevel, got %q", buf.String())
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	if l, err := ParseLevel("warn"); err != nil || l != slog.LevelWarn {
+		t.Errorf("Expected warn level, got %v, %v", l, err)
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("Expected error for unknown level")
+	}
+}
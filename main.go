@@ -1,42 +1,221 @@
 package main
 
 import (
+	"bufio"
+	"errors"
+	"flag"
 	"fmt"
 	"github.com/TsSol87/calendarApp/calendar"
 	"github.com/TsSol87/calendarApp/cmd"
+	"github.com/TsSol87/calendarApp/config"
 	"github.com/TsSol87/calendarApp/logger"
+	"github.com/TsSol87/calendarApp/rotate"
 	"github.com/TsSol87/calendarApp/storage"
+	"io"
+	"os"
+	"strings"
 	//"github.com/TsSol87/calendarApp/events"
 )
 
 //TIP <p>To run your code, right-click the code and select <b>Run</b>.</p> <p>Alternatively, click
 // the <icon src="AllIcons.Actions.Execute"/> icon in the gutter and select the <b>Run</b> menu item from here.</p>
 
+var log = logger.Component("main")
+
 func main() {
 	defer logger.Close()
-	logger.System("app is started")
+	configPath := flag.String("config", "", "path to the config file")
+	profile := flag.String("profile", os.Getenv(config.EnvProfile), "name of the profile with its own data, history and config")
+	overrides := config.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	cfg, err := loadConfig(*profile, *configPath, overrides)
+	if err != nil {
+		fmt.Println("Config error:", err)
+		return
+	}
+	if err := cfg.EnsureDirs(); err != nil {
+		fmt.Println("Can't create data directories:", err)
+		return
+	}
+	adopted, err := cfg.AdoptLegacyFiles()
+	for _, path := range adopted {
+		fmt.Println("Данные из текущего каталога скопированы в", path)
+	}
+	if err != nil {
+		fmt.Println("Can't copy legacy data files:", err)
+	}
+	dataPath, _ := cfg.DataPath()
+	historyPath, _ := cfg.HistoryPath()
+	logPath, _ := cfg.LogPath()
+	undoPath, _ := cfg.UndoPath()
+	auditPath, _ := cfg.AuditPath()
+	if err := logger.Init(logPath, cfg.LogOptions()); err != nil {
+		fmt.Println("Failed to open log file:", err)
+		return
+	}
+
+	log.Info("app is started", "profile", cfg.Profile(), "storage", cfg.Storage)
 	fmt.Println("Введите команду... или введите help для справки")
-	s := storage.NewJsonStorage("calendar_data.json")
+	if cfg.Profile() != config.DefaultProfile {
+		fmt.Println("Профиль:", cfg.Profile())
+	}
+	s, history, err := storage.Open(cfg.Storage, dataPath, historyPath, cfg.StorageOptions())
+	if err != nil {
+		log.Error("storage opening error", "storage", cfg.Storage, "err", err)
+		fmt.Println("Error:", err)
+		return
+	}
+	if closer, ok := s.(io.Closer); ok {
+		defer closer.Close()
+	}
+	if closer, ok := history.(io.Closer); ok {
+		defer closer.Close()
+	}
+	undo := storage.Store(storage.NewJsonStorage(undoPath))
+	auditFile := storage.NewHistoryFile(auditPath, rotate.Options{})
+	defer auditFile.Close()
+	audit := storage.History(auditFile)
+	if cfg.Encryption != config.EncryptionNone {
+		s, history, audit, undo, err = openEncrypted(cfg, s, history, audit, undo)
+		if err != nil {
+			log.Error("encrypted data opening error", "file", s.GetFilename(), "err", err)
+			fmt.Println("Error:", err)
+			return
+		}
+	}
 
-	//zs := storage.NewZipStorage("calendar_data.zip")
-	c := calendar.NewCalendar(s)
-	err := c.Load()
+	c := calendar.NewCalendar(storage.NewRepository(s))
+	err = c.Load()
+	if errors.Is(err, storage.ErrCorrupted) && offerRecovery(s, err) {
+		err = c.Load()
+	}
 	if err != nil {
-		logMessage := fmt.Sprintf("Data upload error: (file: %s): %v", s.GetFilename(), err)
-		logger.Error(logMessage)
+		log.Error("data loading error", "file", s.GetFilename(), "err", err)
 		fmt.Println("Data upload error:", err)
+		if errors.Is(err, storage.ErrLocked) {
+			fmt.Println("Данные уже открыты в другом окне программы")
+		}
 		return
 	}
 
-	cli := cmd.NewCmd(c)
+	c.EnableAudit(audit, cfg.ActorName())
+	if err := c.EnableUndo(undo, cfg.UndoDepth); err != nil {
+		log.Error("undo log loading error", "file", undoPath, "err", err)
+		fmt.Println("Не удалось загрузить журнал отмены:", err)
+	}
+
+	cli := cmd.NewCmd(c, cfg, history)
 	cli.Run()
 	defer func() {
 		err := c.Save()
 		if err != nil {
-			logMessage := fmt.Sprintf("Data saving error: (file: %s): %v", s.GetFilename(), err)
-			logger.Error(logMessage)
+			log.Error("data saving error", "file", s.GetFilename(), "err", err)
 			fmt.Println("Error:", err)
 		}
 	}()
 
 }
+
+// offerRecovery спрашивает пользователя, восстановить ли повреждённый файл данных из резервной копии.
+func offerRecovery(s storage.Store, loadErr error) bool {
+	r, ok := s.(storage.Recoverable)
+	if !ok {
+		return false
+	}
+	if _, err := os.Stat(r.BackupFilename()); err != nil {
+		return false
+	}
+	log.Error("data file is corrupted", "file", s.GetFilename(), "err", loadErr)
+	fmt.Println("Ошибка:", loadErr)
+	if !confirm(fmt.Sprintf("Восстановить данные из резервной копии %s?", r.BackupFilename())) {
+		return false
+	}
+	if err := r.Recover(); err != nil {
+		log.Error("data recovery error", "file", r.BackupFilename(), "err", err)
+		fmt.Println("Не удалось восстановить данные:", err)
+		return false
+	}
+	log.Info("data restored from backup", "file", r.BackupFilename())
+	fmt.Println("Данные восстановлены из резервной копии")
+	return true
+}
+
+func loadConfig(profile, path string, overrides *config.Overrides) (*config.Config, error) {
+	cfg, err := config.Load(profile, path)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.ApplyEnv(); err != nil {
+		return nil, err
+	}
+	if err := overrides.Apply(cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.Apply(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N]: ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes" || answer == "д" || answer == "да"
+}
+
+// openEncrypted оборачивает хранилища в шифрующие, запрашивая пароль или читая файл ключа.
+// Неверный пароль можно ввести заново; незашифрованные данные шифруются с согласия пользователя.
+func openEncrypted(cfg *config.Config, s storage.Store, history, audit storage.History, undo storage.Store) (storage.Store, storage.History, storage.History, storage.Store, error) {
+	_, statErr := os.Stat(s.GetFilename())
+	create := errors.Is(statErr, os.ErrNotExist)
+	_, envSet := os.LookupEnv(cmd.EnvPassphrase)
+	retry := cfg.Encryption == config.EncryptionPassphrase && !envSet
+
+	var secret storage.Secret
+	var data *storage.EncryptedStorage
+	for attempt := 1; ; attempt++ {
+		var err error
+		secret, err = cmd.EncryptionSecret(cfg, create)
+		if err != nil {
+			return s, nil, nil, nil, err
+		}
+		data = storage.NewEncryptedStorage(s, secret)
+		if create {
+			break
+		}
+		_, err = data.Load()
+		if errors.Is(err, storage.ErrDecrypt) && retry && attempt < 3 {
+			fmt.Println("Неверный пароль, попробуйте ещё раз")
+			continue
+		}
+		if errors.Is(err, storage.ErrNotEncrypted) {
+			if !confirm("Файл данных не зашифрован. Зашифровать его сейчас?") {
+				return s, nil, nil, nil, err
+			}
+			err = data.Encrypt()
+		}
+		if err != nil && !errors.Is(err, storage.ErrCorrupted) && !errors.Is(err, os.ErrNotExist) {
+			return s, nil, nil, nil, err
+		}
+		break
+	}
+
+	var logs []storage.History
+	for _, plain := range []storage.History{history, audit} {
+		e := data.History(plain)
+		if err := e.Encrypt(); err != nil {
+			return s, nil, nil, nil, fmt.Errorf("%s: %w", plain.GetFilename(), err)
+		}
+		logs = append(logs, e)
+	}
+	encryptedUndo := storage.NewEncryptedStorage(undo, secret)
+	if err := encryptedUndo.Encrypt(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return s, nil, nil, nil, fmt.Errorf("%s: %w", undo.GetFilename(), err)
+	}
+	if err := cmd.EncryptSnapshots(cfg, data); err != nil {
+		return s, nil, nil, nil, fmt.Errorf("snapshots: %w", err)
+	}
+	return data, logs[0], logs[1], encryptedUndo, nil
+}
@@ -22,3 +22,16 @@ func (p Priority) Validate() error {
 		return ErrIsValidPriority
 	}
 }
+
+func (p Priority) Marker() string {
+	switch p {
+	case PriorityHigh:
+		return "!"
+	case PriorityMedium:
+		return "*"
+	case PriorityLow:
+		return "·"
+	default:
+		return "?"
+	}
+}
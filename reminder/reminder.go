@@ -18,6 +18,13 @@ type Reminder struct {
 var ErrEmptyMessage = errors.New("message is empty")
 
 func (r *Reminder) String() string {
+	if r == nil {
+		return "не установлено"
+	}
+	return r.Format(r.At.Location())
+}
+
+func (r *Reminder) Format(loc *time.Location) string {
 	if r == nil {
 		return "не установлено"
 	}
@@ -27,7 +34,7 @@ func (r *Reminder) String() string {
 	}
 	return fmt.Sprintf("\"%s\", Время: %s, Статус: %s",
 		r.Message,
-		r.At.Format("2006-01-02 15:04:05"),
+		r.At.In(loc).Format("2006-01-02 15:04:05"),
 		status,
 	)
 }
@@ -58,6 +65,21 @@ func (r *Reminder) Start() {
 	}
 }
 
+// Resume перезапускает таймер после загрузки из хранилища; пропущенные напоминания отправляются сразу.
+func (r *Reminder) Resume(notify func(msg string)) {
+	if r.Sent {
+		return
+	}
+	r.notify = notify
+	duration := time.Until(r.At)
+	if duration < 0 {
+		duration = 0
+	}
+	r.Timer = time.AfterFunc(duration, func() {
+		r.Send()
+	})
+}
+
 func (r *Reminder) Send() {
 	if r.Sent {
 		return
@@ -65,7 +87,6 @@ func (r *Reminder) Send() {
 	r.notify(r.Message)
 
 	r.Sent = true
-	fmt.Println("Проверка статуса", r.Sent)
 }
 
 func (r *Reminder) Stop() {
@@ -0,0 +1,352 @@
+// Package rotate пишет файл, который по размеру или возрасту переименовывается в архивную часть,
+// сжимается и удаляется по правилам хранения. Им пользуются журнал приложения и история команд.
+package rotate
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	stampLayout = "20060102-150405.000"
+	GzipSuffix  = ".gz"
+)
+
+// Options — правила ротации; нулевое значение поля отключает соответствующее правило.
+type Options struct {
+	MaxSize  int64         // размер текущего файла в байтах, после которого начинается новая часть
+	Every    time.Duration // как долго пишется одна часть
+	MaxAge   time.Duration // архивные части старше удаляются
+	MaxFiles int           // сколько архивных частей хранить
+	Compress bool          // сжимать архивные части gzip
+}
+
+// File дописывает данные в файл и ротирует его перед записью, которая нарушила бы Options.
+// Запись за один вызов Write не делится между частями.
+type File struct {
+	name string
+	opt  Options
+	now  func() time.Time
+
+	mutex sync.Mutex
+	file  *os.File
+	size  int64
+	start time.Time
+}
+
+func Open(name string, opt Options) (*File, error) {
+	f := &File{name: name, opt: opt, now: time.Now}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *File) Name() string {
+	return f.name
+}
+
+// open открывает текущую часть; её начало — время последней ротации или изменения файла.
+func (f *File) open() error {
+	file, err := os.OpenFile(f.name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	f.start = f.now()
+	if f.size > 0 {
+		f.start = info.ModTime()
+		if list, err := Backups(f.name); err == nil && len(list) > 0 {
+			if at, ok := stamp(f.name, list[len(list)-1]); ok {
+				f.start = at
+			}
+		}
+	}
+	return nil
+}
+
+func (f *File) Write(p []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if err := f.reopenIfMoved(); err != nil {
+		return 0, err
+	}
+	if f.due(int64(len(p))) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// reopenIfMoved открывает файл заново, если его ротировал другой процесс.
+func (f *File) reopenIfMoved() error {
+	current, err := f.file.Stat()
+	if err != nil {
+		return err
+	}
+	onDisk, err := os.Stat(f.name)
+	if err == nil && os.SameFile(current, onDisk) {
+		return nil
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	f.file.Close()
+	return f.open()
+}
+
+func (f *File) due(next int64) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.opt.MaxSize > 0 && f.size+next > f.opt.MaxSize {
+		return true
+	}
+	return f.opt.Every > 0 && f.now().Sub(f.start) >= f.opt.Every
+}
+
+// Rotate начинает новую часть, даже если правила этого не требуют.
+func (f *File) Rotate() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	return f.rotate()
+}
+
+func (f *File) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+	now := f.now()
+	archived := f.partName(now)
+	if err := os.Rename(f.name, archived); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	f.start = now
+	return f.cleanup(now)
+}
+
+// partName возвращает имя архивной части, не занятое ни сжатой, ни несжатой частью:
+// при двух ротациях за одну миллисекунду время второй сдвигается вперёд.
+func (f *File) partName(at time.Time) string {
+	for {
+		name := f.name + "." + at.UTC().Format(stampLayout)
+		_, err := os.Stat(name)
+		_, errGzip := os.Stat(name + GzipSuffix)
+		if errors.Is(err, os.ErrNotExist) && errors.Is(errGzip, os.ErrNotExist) {
+			return name
+		}
+		at = at.Add(time.Millisecond)
+	}
+}
+
+// cleanup сжимает архивные части и удаляет лишние: сверх MaxFiles и старше MaxAge.
+func (f *File) cleanup(now time.Time) error {
+	list, err := Backups(f.name)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	keep := len(list)
+	if f.opt.MaxFiles > 0 && keep > f.opt.MaxFiles {
+		keep = f.opt.MaxFiles
+	}
+	for i, path := range list {
+		at, _ := stamp(f.name, path)
+		if i < len(list)-keep || (f.opt.MaxAge > 0 && now.Sub(at) > f.opt.MaxAge) {
+			errs = append(errs, os.Remove(path))
+			continue
+		}
+		if f.opt.Compress && !strings.HasSuffix(path, GzipSuffix) {
+			errs = append(errs, compress(path))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (f *File) Sync() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	return f.file.Sync()
+}
+
+func (f *File) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// Backups возвращает архивные части файла name от старых к новым.
+func Backups(name string) ([]string, error) {
+	matches, err := filepath.Glob(escape(name) + ".*")
+	if err != nil {
+		return nil, err
+	}
+	var list []string
+	for _, path := range matches {
+		if _, ok := stamp(name, path); ok {
+			list = append(list, path)
+		}
+	}
+	sort.Strings(list)
+	return list, nil
+}
+
+// stamp извлекает время ротации из имени архивной части: name.20060102-150405.000[.gz].
+func stamp(name, path string) (time.Time, bool) {
+	rest, ok := strings.CutPrefix(path, name+".")
+	if !ok {
+		return time.Time{}, false
+	}
+	rest = strings.TrimSuffix(rest, GzipSuffix)
+	at, err := time.Parse(stampLayout, rest)
+	return at, err == nil
+}
+
+// escape экранирует метасимволы шаблона в имени файла; в Windows обратная косая черта — разделитель пути.
+func escape(name string) string {
+	if filepath.Separator == '\\' {
+		return name
+	}
+	return strings.NewReplacer(`*`, `\*`, `?`, `\?`, `[`, `\[`).Replace(name)
+}
+
+func compress(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	tmp := path + GzipSuffix + ".tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if errClose := zw.Close(); err == nil {
+		err = errClose
+	}
+	if errSync := dst.Sync(); err == nil {
+		err = errSync
+	}
+	if errClose := dst.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Rename(tmp, path+GzipSuffix)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("can't compress %s: %w", path, err)
+	}
+	src.Close()
+	return os.Remove(path)
+}
+
+// OpenPart открывает часть файла для чтения, распаковывая сжатые.
+func OpenPart(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, GzipSuffix) {
+		return f, nil
+	}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &gzipPart{zr, f}, nil
+}
+
+type gzipPart struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (g *gzipPart) Close() error {
+	return errors.Join(g.Reader.Close(), g.file.Close())
+}
+
+// Parts возвращает архивные части и текущий файл в порядке записи.
+func Parts(name string) ([]string, error) {
+	list, err := Backups(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(name); err == nil {
+		list = append(list, name)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return list, nil
+}
+
+// WritePart заменяет содержимое части data, сжимая его, если часть сжата.
+func WritePart(path string, data []byte) error {
+	if strings.HasSuffix(path, GzipSuffix) {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(data)
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		data = buf.Bytes()
+	}
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if errSync := f.Sync(); err == nil {
+		err = errSync
+	}
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
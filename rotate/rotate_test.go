@@ -0,0 +1,118 @@
+package rotate
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readParts(t *testing.T, name string) string {
+	t.Helper()
+	parts, err := Parts(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var all strings.Builder
+	for _, path := range parts {
+		r, err := OpenPart(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		all.Write(data)
+	}
+	return all.String()
+}
+
+func TestFile_RotatesBySizeAndCompresses(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	f, err := Open(name, Options{MaxSize: 10, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	f.now = func() time.Time { clock = clock.Add(time.Second); return clock }
+	defer f.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backups, err := Backups(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("Expected 2 rotated parts, got %v", backups)
+	}
+	for _, path := range backups {
+		if !strings.HasSuffix(path, GzipSuffix) {
+			t.Errorf("Expected rotated part %s to be compressed", path)
+		}
+	}
+	if got := readParts(t, name); got != "first\nsecond\nthird\n" {
+		t.Errorf("Expected all lines in order, got %q", got)
+	}
+}
+
+func TestFile_Retention(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	f, err := Open(name, Options{Every: time.Hour, MaxFiles: 2, MaxAge: 3 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	f.now = func() time.Time { return clock }
+	f.start = clock
+	defer f.Close()
+
+	for i := 0; i < 4; i++ {
+		f.Write([]byte("line\n"))
+		clock = clock.Add(time.Hour)
+	}
+	backups, _ := Backups(name)
+	if len(backups) != 2 {
+		t.Fatalf("Expected MaxFiles to keep 2 parts, got %v", backups)
+	}
+
+	clock = clock.Add(5 * time.Hour)
+	f.Write([]byte("late\n"))
+	backups, _ = Backups(name)
+	if len(backups) != 1 {
+		t.Errorf("Expected parts older than MaxAge to be removed, got %v", backups)
+	}
+	if data, _ := os.ReadFile(name); string(data) != "late\n" {
+		t.Errorf("Expected the current part to contain only the last write, got %q", data)
+	}
+}
+
+func TestFile_RotationsWithinOneMillisecond(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	f, err := Open(name, Options{MaxSize: 10, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	f.now = func() time.Time { return clock }
+	defer f.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if backups, _ := Backups(name); len(backups) != 2 {
+		t.Errorf("Expected 2 rotated parts with distinct names, got %v", backups)
+	}
+	if got := readParts(t, name); got != "first\nsecond\nthird\n" {
+		t.Errorf("Expected no part to be overwritten, got %q", got)
+	}
+}
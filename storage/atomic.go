@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const BackupSuffix = ".bak"
+
+var ErrCorrupted = errors.New("data file is corrupted")
+
+// writeFileAtomic пишет данные во временный файл рядом с целевым, сбрасывает их на диск
+// и атомарно подменяет целевой файл. Предыдущая версия остаётся в filename+".bak".
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(filename)
+	tmp, err := os.CreateTemp(dir, filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return err
+	}
+
+	if err := backup(filename); err != nil {
+		return fmt.Errorf("can't keep backup of %s: %w", filename, err)
+	}
+	if err := os.Rename(tmpName, filename); err != nil {
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+func backup(filename string) error {
+	if _, err := os.Stat(filename); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	bak := filename + BackupSuffix
+	if err := os.Remove(bak); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.Link(filename, bak); err == nil {
+		return nil
+	}
+	return copyFile(filename, bak)
+}
+
+// removeBackup удаляет резервную копию хранилища s, если она есть. После шифрования или смены ключа
+// в копии остались бы данные, которые открываются без ключа или прежним ключом.
+func removeBackup(s any) error {
+	b, ok := s.(interface{ BackupFilename() string })
+	if !ok || b.BackupFilename() == "" {
+		return nil
+	}
+	if err := os.Remove(b.BackupFilename()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("can't remove backup: %w", err)
+	}
+	return nil
+}
+
+func copyFile(from, to string) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(to, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Sync(); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// syncDir сохраняет переименование на диске; на системах, где каталог нельзя открыть, ничего не делает.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
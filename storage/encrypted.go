@@ -0,0 +1,567 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	EncryptedFormat = "calendarApp-encrypted"
+	KDFScrypt       = "scrypt"
+	KDFKeyFile      = "keyfile"
+	keySize         = 32
+	saltSize        = 16
+)
+
+var (
+	// ErrDecrypt возвращается и при неверном ключе, и при изменённых данных: AES-GCM их не различает.
+	ErrDecrypt      = errors.New("can't decrypt data: wrong passphrase or key, or the data was tampered with")
+	ErrNotEncrypted = errors.New("data file is not encrypted")
+)
+
+// ScryptParams — стоимость получения ключа из пароля; сохраняется в заголовке файла.
+type ScryptParams struct {
+	N int `json:"n"`
+	R int `json:"r"`
+	P int `json:"p"`
+}
+
+var DefaultScryptParams = ScryptParams{N: 1 << 15, R: 8, P: 1}
+
+// encryptedHeader аутентифицируется вместе с шифротекстом, поэтому его подмена тоже обнаруживается.
+type encryptedHeader struct {
+	Format  string        `json:"format"`
+	Version int           `json:"version"`
+	KDF     string        `json:"kdf"`
+	Salt    string        `json:"salt,omitempty"`
+	Scrypt  *ScryptParams `json:"scrypt,omitempty"`
+}
+
+type encryptedFile struct {
+	encryptedHeader
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// Secret — пароль или ключ из файла, которым шифруются данные.
+type Secret struct {
+	passphrase []byte
+	key        []byte
+}
+
+func Passphrase(p []byte) Secret {
+	return Secret{passphrase: append([]byte(nil), p...)}
+}
+
+// ReadKeyFile читает ключ из файла: 32 байта в base64.
+func ReadKeyFile(filename string) (Secret, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return Secret{}, err
+	}
+	key, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(data)))
+	if err != nil || len(key) != keySize {
+		return Secret{}, fmt.Errorf("key file %s must contain %d bytes in base64", filename, keySize)
+	}
+	return Secret{key: key}, nil
+}
+
+// GenerateKeyFile создаёт файл со случайным ключом, доступный только владельцу.
+func GenerateKeyFile(filename string) (Secret, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return Secret{}, err
+	}
+	data := base64.StdEncoding.EncodeToString(key) + "\n"
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return Secret{}, err
+	}
+	if _, err := f.WriteString(data); err != nil {
+		f.Close()
+		return Secret{}, err
+	}
+	if err := f.Close(); err != nil {
+		return Secret{}, err
+	}
+	return Secret{key: key}, nil
+}
+
+func (s Secret) kdf() string {
+	if s.key != nil {
+		return KDFKeyFile
+	}
+	return KDFScrypt
+}
+
+// EncryptedStorage шифрует данные другого хранилища (JsonStorage, ZipStorage) с помощью AES-256-GCM.
+type EncryptedStorage struct {
+	inner Store
+
+	mutex  sync.Mutex
+	secret Secret
+	params ScryptParams
+	salt   []byte
+	key    []byte
+}
+
+func NewEncryptedStorage(inner Store, secret Secret) *EncryptedStorage {
+	return &EncryptedStorage{inner: inner, secret: secret, params: DefaultScryptParams}
+}
+
+// WithScryptParams задаёт стоимость scrypt для новых файлов; в тестах позволяет не тратить время на KDF.
+func (e *EncryptedStorage) WithScryptParams(p ScryptParams) *EncryptedStorage {
+	e.params = p
+	return e
+}
+
+// Wrap шифрует другое хранилище тем же секретом, например снимки календаря.
+func (e *EncryptedStorage) Wrap(inner Store) *EncryptedStorage {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return &EncryptedStorage{inner: inner, secret: e.secret, params: e.params, salt: e.salt, key: e.key}
+}
+
+func (e *EncryptedStorage) GetFilename() string {
+	return e.inner.GetFilename()
+}
+
+func (e *EncryptedStorage) Inner() Store {
+	return e.inner
+}
+
+func (e *EncryptedStorage) BackupFilename() string {
+	if r, ok := e.inner.(Recoverable); ok {
+		return r.BackupFilename()
+	}
+	return ""
+}
+
+func (e *EncryptedStorage) Recover() error {
+	r, ok := e.inner.(Recoverable)
+	if !ok {
+		return fmt.Errorf("storage %s can't be recovered", e.GetFilename())
+	}
+	return r.Recover()
+}
+
+func (e *EncryptedStorage) Save(data []byte) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	sealed, err := e.seal(data)
+	if err != nil {
+		return err
+	}
+	return e.inner.Save(sealed)
+}
+
+func (e *EncryptedStorage) Load() ([]byte, error) {
+	data, err := e.inner.Load()
+	if err != nil {
+		return nil, err
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.open(data)
+}
+
+// IsEncrypted сообщает, записаны ли данные этим хранилищем.
+func IsEncrypted(data []byte) bool {
+	var h encryptedHeader
+	return json.Unmarshal(data, &h) == nil && h.Format == EncryptedFormat
+}
+
+// Rotate перешифровывает данные новым секретом со свежей солью.
+// Если записать данные не удалось, хранилище продолжает работать с прежним секретом.
+func (e *EncryptedStorage) Rotate(secret Secret) error {
+	data, err := e.Load()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	next := &EncryptedStorage{inner: e.inner, secret: secret, params: e.params}
+	if data != nil {
+		sealed, err := next.seal(data)
+		if err != nil {
+			return err
+		}
+		if err := e.inner.Save(sealed); err != nil {
+			return err
+		}
+	}
+	e.secret, e.salt, e.key = next.secret, next.salt, next.key
+	return removeBackup(e.inner)
+}
+
+// Secret возвращает секрет, которым сейчас шифруются данные.
+func (e *EncryptedStorage) Secret() Secret {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.secret
+}
+
+// Rotator — зашифрованное хранилище, которое можно перешифровать новым секретом.
+type Rotator interface {
+	GetFilename() string
+	Secret() Secret
+	Rotate(secret Secret) error
+}
+
+var ErrRotationIncomplete = errors.New("key rotation could not be rolled back")
+
+// RotateAll перешифровывает хранилища новым секретом. Если одно из них перешифровать не удалось,
+// уже перешифрованные возвращаются к прежним секретам, чтобы все данные открывались одним ключом.
+// После успеха restore возвращает прежние секреты, например если не удался следующий шаг смены ключа.
+func RotateAll(list []Rotator, secret Secret) (restore func() error, err error) {
+	old := make([]Secret, len(list))
+	for i, r := range list {
+		old[i] = r.Secret()
+	}
+	rollback := func(n int) error {
+		var failed []string
+		var errs []error
+		for i := n - 1; i >= 0; i-- {
+			if err := list[i].Rotate(old[i]); err != nil {
+				failed = append(failed, list[i].GetFilename())
+				errs = append(errs, err)
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("%w: %s still use the new secret: %w", ErrRotationIncomplete, strings.Join(failed, ", "), errors.Join(errs...))
+		}
+		return nil
+	}
+	for i, r := range list {
+		if err := r.Rotate(secret); err != nil {
+			err = fmt.Errorf("%s: %w", r.GetFilename(), err)
+			if errRollback := rollback(i); errRollback != nil {
+				return nil, errors.Join(err, errRollback)
+			}
+			return nil, err
+		}
+	}
+	return func() error { return rollback(len(list)) }, nil
+}
+
+// Encrypt шифрует данные, записанные без шифрования, например при включении шифрования для существующего календаря.
+// Резервная копия с открытыми данными удаляется.
+func (e *EncryptedStorage) Encrypt() error {
+	data, err := e.inner.Load()
+	if err != nil {
+		return err
+	}
+	if IsEncrypted(data) {
+		return nil
+	}
+	if err := e.Save(data); err != nil {
+		return err
+	}
+	return removeBackup(e.inner)
+}
+
+func (e *EncryptedStorage) seal(data []byte) ([]byte, error) {
+	header := encryptedHeader{Format: EncryptedFormat, Version: 1, KDF: e.secret.kdf()}
+	if header.KDF == KDFScrypt {
+		if e.salt == nil {
+			e.salt = make([]byte, saltSize)
+			if _, err := rand.Read(e.salt); err != nil {
+				return nil, err
+			}
+			e.key = nil
+		}
+		params := e.params
+		header.Salt = base64.StdEncoding.EncodeToString(e.salt)
+		header.Scrypt = &params
+	}
+	key, err := e.deriveKey(header)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	ad, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(encryptedFile{
+		encryptedHeader: header,
+		Nonce:           base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext:      base64.StdEncoding.EncodeToString(aead.Seal(nil, nonce, data, ad)),
+	})
+}
+
+func (e *EncryptedStorage) open(data []byte) ([]byte, error) {
+	var file encryptedFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Format != EncryptedFormat {
+		return nil, fmt.Errorf("%w: %s", ErrNotEncrypted, e.GetFilename())
+	}
+	if file.Version != 1 {
+		return nil, fmt.Errorf("unsupported encrypted format version %d", file.Version)
+	}
+	if file.KDF != e.secret.kdf() {
+		return nil, fmt.Errorf("%s is encrypted with %s, but %s was given", e.GetFilename(), file.KDF, e.secret.kdf())
+	}
+	if file.KDF == KDFScrypt {
+		if file.Scrypt == nil {
+			return nil, ErrDecrypt
+		}
+		salt, err := base64.StdEncoding.DecodeString(file.Salt)
+		if err != nil {
+			return nil, ErrDecrypt
+		}
+		if !bytes.Equal(salt, e.salt) || e.params != *file.Scrypt {
+			e.salt = salt
+			e.params = *file.Scrypt
+			e.key = nil
+		}
+	}
+	key, err := e.deriveKey(file.encryptedHeader)
+	if err != nil {
+		return nil, err
+	}
+	nonce, errNonce := base64.StdEncoding.DecodeString(file.Nonce)
+	ciphertext, errText := base64.StdEncoding.DecodeString(file.Ciphertext)
+	if errNonce != nil || errText != nil {
+		return nil, ErrDecrypt
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	ad, err := json.Marshal(file.encryptedHeader)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(nil, nonce, ciphertext, ad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plain, nil
+}
+
+// deriveKey получает ключ из пароля один раз на соль, чтобы каждое сохранение не платило за scrypt.
+func (e *EncryptedStorage) deriveKey(header encryptedHeader) ([]byte, error) {
+	if header.KDF == KDFKeyFile {
+		return e.secret.key, nil
+	}
+	if e.key != nil {
+		return e.key, nil
+	}
+	p := header.Scrypt
+	key, err := scrypt.Key(e.secret.passphrase, e.salt, p.N, p.R, p.P, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("can't derive key: %w", err)
+	}
+	e.key = key
+	return key, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptedHistory шифрует каждую запись истории отдельно, поэтому история по-прежнему только дописывается.
+// Ключ получается из того же секрета и соли, что и у данных, и вычисляется один раз.
+type EncryptedHistory struct {
+	inner History
+
+	mutex sync.Mutex
+	enc   *EncryptedStorage
+}
+
+// History шифрует историю команд тем же секретом, что и данные.
+func (e *EncryptedStorage) History(inner History) *EncryptedHistory {
+	return &EncryptedHistory{inner: inner, enc: e.Wrap(NewJsonStorage(inner.GetFilename()))}
+}
+
+func (h *EncryptedHistory) GetFilename() string {
+	return h.inner.GetFilename()
+}
+
+func (h *EncryptedHistory) Append(record []byte) error {
+	h.mutex.Lock()
+	sealed, err := h.enc.seal(record)
+	h.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	return h.inner.Append(sealed)
+}
+
+func (h *EncryptedHistory) Scan(fn func(record []byte) error) error {
+	return h.inner.Scan(func(sealed []byte) error {
+		records, err := h.open(sealed)
+		if err != nil {
+			return err
+		}
+		for _, r := range records {
+			if err := fn(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// open расшифровывает запись; история, зашифрованная прежде целиком, разворачивается в отдельные записи.
+func (h *EncryptedHistory) open(sealed []byte) ([][]byte, error) {
+	h.mutex.Lock()
+	plain, err := h.enc.open(sealed)
+	h.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(bytes.TrimSpace(plain), []byte("[")) {
+		return [][]byte{plain}, nil
+	}
+	lines, err := splitArray(plain)
+	if err != nil {
+		return nil, fmt.Errorf("%w: history %s: %v", ErrCorrupted, h.GetFilename(), err)
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	return bytes.Split(bytes.TrimSuffix(lines, []byte("\n")), []byte("\n")), nil
+}
+
+// Encrypt шифрует записи, сделанные без шифрования, и перешифровывает записи с другой солью,
+// например историю, зашифрованную прежде целиком: иначе ключ пришлось бы получать для каждой соли.
+// Если история уже зашифрована одним ключом, она не переписывается.
+func (h *EncryptedHistory) Encrypt() error {
+	salts := make(map[string]bool)
+	plain := false
+	var first []byte
+	err := h.inner.Scan(func(record []byte) error {
+		var header encryptedHeader
+		if json.Unmarshal(record, &header) != nil || header.Format != EncryptedFormat {
+			plain = true
+			return io.EOF
+		}
+		if first == nil {
+			first = append([]byte(nil), record...)
+		}
+		salts[header.Salt] = true
+		return nil
+	})
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if !plain && len(salts) <= 1 {
+		// Новые записи шифруются с солью существующих, заодно проверяется ключ.
+		if first != nil {
+			_, err = h.open(first)
+		}
+		return err
+	}
+	return h.reseal(h.enc.secret)
+}
+
+// Rotate перешифровывает все части истории новым секретом.
+func (h *EncryptedHistory) Rotate(secret Secret) error {
+	return h.reseal(secret)
+}
+
+// Secret возвращает секрет, которым сейчас шифруются записи.
+func (h *EncryptedHistory) Secret() Secret {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.enc.Secret()
+}
+
+// reseal переписывает все записи, шифруя их секретом secret с одной новой солью.
+func (h *EncryptedHistory) reseal(secret Secret) error {
+	h.mutex.Lock()
+	next := &EncryptedStorage{inner: h.enc.inner, secret: secret, params: h.enc.params}
+	h.mutex.Unlock()
+	err := h.rewrite(func(record []byte) ([][]byte, error) {
+		records := [][]byte{record}
+		if IsEncrypted(record) {
+			var err error
+			if records, err = h.open(record); err != nil {
+				return nil, err
+			}
+		}
+		for i, r := range records {
+			var err error
+			if records[i], err = next.seal(r); err != nil {
+				return nil, err
+			}
+		}
+		return records, nil
+	})
+	if err != nil {
+		return err
+	}
+	h.mutex.Lock()
+	h.enc = next
+	h.mutex.Unlock()
+	// Копия истории, оставшаяся от переноса из старого формата, не зашифрована.
+	return removeBackup(h.inner)
+}
+
+// Rewrite заменяет каждую расшифрованную запись результатом fn и шифрует записи заново (см. HistoryFile.Rewrite).
+func (h *EncryptedHistory) Rewrite(fn func(record []byte) ([]byte, error)) error {
+	return h.rewrite(func(sealed []byte) ([][]byte, error) {
+		records, err := h.open(sealed)
+		if err != nil {
+			return nil, err
+		}
+		var result [][]byte
+		for _, r := range records {
+			replaced, err := fn(r)
+			if err != nil {
+				return nil, err
+			}
+			for _, line := range bytes.Split(replaced, []byte("\n")) {
+				if line = bytes.TrimSpace(line); len(line) == 0 {
+					continue
+				}
+				h.mutex.Lock()
+				sealed, err := h.enc.seal(line)
+				h.mutex.Unlock()
+				if err != nil {
+					return nil, err
+				}
+				result = append(result, sealed)
+			}
+		}
+		return result, nil
+	})
+}
+
+func (h *EncryptedHistory) rewrite(fn func(record []byte) ([][]byte, error)) error {
+	r, ok := h.inner.(HistoryRewriter)
+	if !ok {
+		return fmt.Errorf("history %s can't be rewritten", h.GetFilename())
+	}
+	return r.Rewrite(func(record []byte) ([]byte, error) {
+		records, err := fn(record)
+		return bytes.Join(records, []byte("\n")), err
+	})
+}
@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TsSol87/calendarApp/rotate"
+)
+
+var testScrypt = ScryptParams{N: 1 << 10, R: 8, P: 1}
+
+func TestEncryptedStorage_RoundTripAndWrongPassphrase(t *testing.T) {
+	inner := NewJsonStorage(filepath.Join(t.TempDir(), "data.json"))
+	s := NewEncryptedStorage(inner, Passphrase([]byte("secret"))).WithScryptParams(testScrypt)
+	if err := s.Save([]byte(`{"client":"Acme"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, _ := os.ReadFile(inner.GetFilename())
+	if bytes.Contains(raw, []byte("Acme")) || !IsEncrypted(raw) {
+		t.Fatalf("Expected encrypted file, got %s", raw)
+	}
+	data, err := NewEncryptedStorage(inner, Passphrase([]byte("secret"))).Load()
+	if err != nil || string(data) != `{"client":"Acme"}` {
+		t.Errorf("Expected decrypted data, got %q (%v)", data, err)
+	}
+	if _, err := NewEncryptedStorage(inner, Passphrase([]byte("wrong"))).Load(); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Expected ErrDecrypt for wrong passphrase, got %v", err)
+	}
+}
+
+func TestEncryptedStorage_Tampering(t *testing.T) {
+	inner := NewJsonStorage(filepath.Join(t.TempDir(), "data.json"))
+	s := NewEncryptedStorage(inner, Passphrase([]byte("secret"))).WithScryptParams(testScrypt)
+	if err := s.Save([]byte(`{"v":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := os.ReadFile(inner.GetFilename())
+
+	tamper := map[string]func(f *encryptedFile){
+		"ciphertext": func(f *encryptedFile) {
+			c, _ := base64.StdEncoding.DecodeString(f.Ciphertext)
+			c[0] ^= 1
+			f.Ciphertext = base64.StdEncoding.EncodeToString(c)
+		},
+		"header": func(f *encryptedFile) { f.Scrypt.N = 1 << 11 },
+	}
+	for name, change := range tamper {
+		t.Run(name, func(t *testing.T) {
+			var f encryptedFile
+			json.Unmarshal(raw, &f)
+			change(&f)
+			data, _ := json.Marshal(f)
+			os.WriteFile(inner.GetFilename(), data, 0644)
+			if _, err := NewEncryptedStorage(inner, Passphrase([]byte("secret"))).Load(); !errors.Is(err, ErrDecrypt) {
+				t.Errorf("Expected ErrDecrypt after tampering, got %v", err)
+			}
+		})
+	}
+}
+
+func TestEncryptedStorage_KeyFileAndRotate(t *testing.T) {
+	dir := t.TempDir()
+	secret, err := GenerateKeyFile(filepath.Join(dir, "key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(filepath.Join(dir, "key")); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected key file mode 0600, got %v", info.Mode().Perm())
+	}
+	inner := NewZipStorage(filepath.Join(dir, "data.zip"))
+	s := NewEncryptedStorage(inner, secret)
+	if err := s.Save([]byte(`{"v":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadKeyFile(filepath.Join(dir, "key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := NewEncryptedStorage(inner, read).Load(); err != nil || string(data) != `{"v":1}` {
+		t.Fatalf("Expected data readable with key file, got %q (%v)", data, err)
+	}
+
+	if err := s.WithScryptParams(testScrypt).Rotate(Passphrase([]byte("new"))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewEncryptedStorage(inner, read).Load(); err == nil {
+		t.Errorf("Expected old key to stop working after rotation")
+	}
+	if data, err := NewEncryptedStorage(inner, Passphrase([]byte("new"))).Load(); err != nil || string(data) != `{"v":1}` {
+		t.Errorf("Expected data readable with new passphrase, got %q (%v)", data, err)
+	}
+}
+
+func TestEncryptedStorage_EncryptPlaintext(t *testing.T) {
+	inner := NewJsonStorage(filepath.Join(t.TempDir(), "data.json"))
+	if err := inner.Save([]byte(`{"v":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	s := NewEncryptedStorage(inner, Passphrase([]byte("secret"))).WithScryptParams(testScrypt)
+	if _, err := s.Load(); !errors.Is(err, ErrNotEncrypted) {
+		t.Fatalf("Expected ErrNotEncrypted for plaintext file, got %v", err)
+	}
+	if err := s.Encrypt(); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := s.Load(); err != nil || string(data) != `{"v":1}` {
+		t.Errorf("Expected encrypted copy of plaintext data, got %q (%v)", data, err)
+	}
+}
+
+// plaintextFiles возвращает файлы каталога dir, в которых text виден открытым текстом.
+func plaintextFiles(t *testing.T, dir, text string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []string
+	for _, entry := range entries {
+		if data, err := os.ReadFile(filepath.Join(dir, entry.Name())); err == nil && bytes.Contains(data, []byte(text)) {
+			found = append(found, entry.Name())
+		}
+	}
+	return found
+}
+
+func TestEncrypt_LeavesNoReadableBackups(t *testing.T) {
+	dir := t.TempDir()
+	inner := NewJsonStorage(filepath.Join(dir, "cal.json"))
+	// Вторая запись оставляет первую в cal.json.bak.
+	for _, data := range []string{`{"title":"Client Acme"}`, `{"title":"Client Acme","v":2}`} {
+		if err := inner.Save([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "log.json"), []byte(`[{"Message":"add Client Acme"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	history := NewHistoryFile(filepath.Join(dir, "log.json"), rotate.Options{})
+	defer history.Close()
+
+	s := NewEncryptedStorage(inner, Passphrase([]byte("old"))).WithScryptParams(testScrypt)
+	if err := s.Encrypt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.History(history).Encrypt(); err != nil {
+		t.Fatal(err)
+	}
+	if found := plaintextFiles(t, dir, "Client Acme"); len(found) > 0 {
+		t.Fatalf("Expected no plaintext left after encryption, found it in %v", found)
+	}
+
+	if err := s.Save([]byte(`{"title":"Client Acme","v":3}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Rotate(Passphrase([]byte("new"))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(inner.BackupFilename()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected no backup readable with the old passphrase after rotation, got %v", err)
+	}
+	if data, err := NewEncryptedStorage(inner, Passphrase([]byte("new"))).Load(); err != nil || string(data) != `{"title":"Client Acme","v":3}` {
+		t.Errorf("Expected data readable with the new passphrase, got %q (%v)", data, err)
+	}
+}
+
+// appendOnly скрывает Rewrite, поэтому такую историю нельзя перешифровать.
+type appendOnly struct {
+	History
+}
+
+func TestRotateAll_RollsBackOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "data.json")
+	data := NewEncryptedStorage(NewJsonStorage(filename), Passphrase([]byte("old"))).WithScryptParams(testScrypt)
+	if err := data.Save([]byte(`{"client":"Acme"}`)); err != nil {
+		t.Fatal(err)
+	}
+	history := NewHistoryFile(filepath.Join(dir, "log_data.json"), rotate.Options{})
+	defer history.Close()
+	broken := data.History(appendOnly{history})
+	if err := broken.Append([]byte(`{"Message":"add"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	restore, err := RotateAll([]Rotator{data, broken}, Passphrase([]byte("new")))
+	if err == nil || restore != nil {
+		t.Fatalf("Expected rotation to fail on the history, got %v", err)
+	}
+	if errors.Is(err, ErrRotationIncomplete) {
+		t.Errorf("Expected the data to be rolled back, got %v", err)
+	}
+	old := NewEncryptedStorage(NewJsonStorage(filename), Passphrase([]byte("old")))
+	if got, err := old.Load(); err != nil || string(got) != `{"client":"Acme"}` {
+		t.Errorf("Expected the data to open with the old passphrase, got %q (%v)", got, err)
+	}
+	if err := broken.Scan(func([]byte) error { return nil }); err != nil {
+		t.Errorf("Expected the history to stay readable, got %v", err)
+	}
+
+	// Успешную смену ключа можно откатить, если не удался следующий шаг.
+	restore, err = RotateAll([]Rotator{data}, Passphrase([]byte("new")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := old.Load(); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Expected the old passphrase to fail after rotation, got %v", err)
+	}
+	if err := restore(); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := old.Load(); err != nil || string(got) != `{"client":"Acme"}` {
+		t.Errorf("Expected the old passphrase to work after restore, got %q (%v)", got, err)
+	}
+}
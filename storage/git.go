@@ -0,0 +1,417 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TsSol87/calendarApp/events"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+const gitEventsDir = "events"
+
+// Annotator принимает описание следующего изменения (например, введённую команду) для истории хранилища.
+type Annotator interface {
+	Annotate(message string)
+}
+
+// Revision — состояние события после одного коммита; Event равен nil, если событие в нём удалено.
+type Revision struct {
+	Hash    string
+	Time    time.Time
+	Author  string
+	Message string
+	Event   *events.Event
+}
+
+// Versioned хранит историю изменений каждого события.
+type Versioned interface {
+	History(id string) ([]Revision, error)
+}
+
+// GitStorage хранит каждое событие отдельным файлом events/<id>.json в локальном git-репозитории
+// и коммитит каждую транзакцию с описанием изменения. Репозиторий можно смотреть и обычным git.
+// Как и журнал, репозиторий принадлежит одному процессу.
+type GitStorage struct {
+	dir string
+
+	mutex      sync.Mutex
+	repo       *git.Repository
+	lock       *FileLock
+	records    map[string]json.RawMessage
+	annotation string
+}
+
+func NewGitStorage(dir string) *GitStorage {
+	return &GitStorage{dir: dir}
+}
+
+func (g *GitStorage) GetFilename() string {
+	return g.dir
+}
+
+// Annotate задаёт текст, который попадёт в сообщения следующих коммитов; пустая строка его сбрасывает.
+func (g *GitStorage) Annotate(message string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.annotation = message
+}
+
+func (g *GitStorage) Close() error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	err := g.lock.Unlock()
+	g.lock = nil
+	return err
+}
+
+func (g *GitStorage) load() error {
+	if g.repo != nil {
+		return nil
+	}
+	if err := os.MkdirAll(g.dir, 0755); err != nil {
+		return err
+	}
+	if g.lock == nil {
+		l, err := TryLockFile(g.dir, true)
+		if err != nil {
+			return err
+		}
+		g.lock = l
+	}
+	repo, err := git.PlainOpen(g.dir)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		repo, err = git.PlainInit(g.dir, false)
+	}
+	if err != nil {
+		return fmt.Errorf("can't open git repository %s: %w", g.dir, err)
+	}
+
+	records := make(map[string]json.RawMessage)
+	head, err := g.head(repo)
+	if err != nil {
+		return err
+	}
+	if head != nil {
+		// Изменения, которые не успели закоммитить до сбоя, отбрасываются: источник истины — HEAD.
+		wt, err := repo.Worktree()
+		if err != nil {
+			return err
+		}
+		if err := wt.Reset(&git.ResetOptions{Mode: git.HardReset}); err != nil {
+			return fmt.Errorf("can't reset worktree: %w", err)
+		}
+		files, err := head.Files()
+		if err != nil {
+			return err
+		}
+		err = files.ForEach(func(f *object.File) error {
+			id, ok := eventID(f.Name)
+			if !ok {
+				return nil
+			}
+			content, err := f.Contents()
+			if err != nil {
+				return err
+			}
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, []byte(content)); err != nil {
+				return fmt.Errorf("%w: %s: %v", ErrCorrupted, f.Name, err)
+			}
+			records[id] = compact.Bytes()
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	g.repo = repo
+	g.records = records
+	return nil
+}
+
+// head возвращает коммит HEAD; nil, если коммитов ещё нет.
+func (g *GitStorage) head(repo *git.Repository) (*object.Commit, error) {
+	ref, err := repo.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return repo.CommitObject(ref.Hash())
+}
+
+func eventID(name string) (string, bool) {
+	dir, file := path.Split(name)
+	if dir != gitEventsDir+"/" {
+		return "", false
+	}
+	return strings.CutSuffix(file, ".json")
+}
+
+func eventPath(id string) string {
+	return path.Join(gitEventsDir, id+".json")
+}
+
+func (g *GitStorage) Save(data []byte) error {
+	list, _, err := DecodeCalendar(data)
+	if err != nil {
+		return err
+	}
+	return g.Transaction(func(tx Repository) error {
+		current, err := tx.All()
+		if err != nil {
+			return err
+		}
+		for id := range current {
+			if _, ok := list[id]; !ok {
+				if err := tx.Delete(id); err != nil {
+					return err
+				}
+			}
+		}
+		for _, e := range list {
+			if err := tx.Put(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (g *GitStorage) Load() ([]byte, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if err := g.load(); err != nil {
+		return nil, err
+	}
+	return EncodeCalendar(g.records)
+}
+
+// Migrate ничего не переписывает: файлы событий всегда пишутся в текущем формате.
+func (g *GitStorage) Migrate(dryRun bool) (*MigrationReport, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if err := g.load(); err != nil {
+		return nil, err
+	}
+	return &MigrationReport{From: SchemaVersion, To: SchemaVersion, Events: len(g.records)}, nil
+}
+
+func (g *GitStorage) Get(id string) (*events.Event, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if err := g.load(); err != nil {
+		return nil, err
+	}
+	return decodeEvent(g.records, id)
+}
+
+func (g *GitStorage) Put(e *events.Event) error {
+	return g.Transaction(func(tx Repository) error { return tx.Put(e) })
+}
+
+func (g *GitStorage) Delete(id string) error {
+	return g.Transaction(func(tx Repository) error { return tx.Delete(id) })
+}
+
+func (g *GitStorage) Range(from, to time.Time) ([]*events.Event, error) {
+	list, err := g.All()
+	if err != nil {
+		return nil, err
+	}
+	return rangeEvents(list, from, to), nil
+}
+
+func (g *GitStorage) All() (map[string]*events.Event, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if err := g.load(); err != nil {
+		return nil, err
+	}
+	return decodeEvents(g.records)
+}
+
+// Transaction записывает изменённые файлы событий и коммитит их одним коммитом.
+func (g *GitStorage) Transaction(fn func(tx Repository) error) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if err := g.load(); err != nil {
+		return err
+	}
+
+	tx := &journalTx{records: make(map[string]json.RawMessage, len(g.records))}
+	for id, raw := range g.records {
+		tx.records[id] = raw
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if len(tx.ops) == 0 {
+		return nil
+	}
+
+	if err := g.commit(tx); err != nil {
+		if wt, errWt := g.repo.Worktree(); errWt == nil {
+			wt.Reset(&git.ResetOptions{Mode: git.HardReset})
+		}
+		return fmt.Errorf("can't commit to %s: %w", g.dir, err)
+	}
+	g.records = tx.records
+	return nil
+}
+
+func (g *GitStorage) commit(tx *journalTx) error {
+	wt, err := g.repo.Worktree()
+	if err != nil {
+		return err
+	}
+	changed := make(map[string]bool)
+	for _, op := range tx.ops {
+		changed[op.ID] = true
+	}
+	if err := os.MkdirAll(filepath.Join(g.dir, gitEventsDir), 0755); err != nil {
+		return err
+	}
+	for id := range changed {
+		name := eventPath(id)
+		raw, exists := tx.records[id]
+		if !exists {
+			if _, ok := g.records[id]; ok {
+				if _, err := wt.Remove(name); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, raw, "", "  "); err != nil {
+			return err
+		}
+		pretty.WriteByte('\n')
+		if err := os.WriteFile(filepath.Join(g.dir, filepath.FromSlash(name)), pretty.Bytes(), 0644); err != nil {
+			return err
+		}
+		if _, err := wt.Add(name); err != nil {
+			return err
+		}
+	}
+
+	_, err = wt.Commit(g.message(tx), &git.CommitOptions{Author: signature(), AllowEmptyCommits: true})
+	return err
+}
+
+// message описывает транзакцию: первая строка — что изменилось, затем команда, которая это сделала.
+func (g *GitStorage) message(tx *journalTx) string {
+	verbs := map[string]string{OpAdd: "Add", OpUpdate: "Update", OpReminder: "Set reminder of", OpDelete: "Delete"}
+	var lines []string
+	for _, op := range tx.ops {
+		raw := op.Event
+		if raw == nil {
+			raw = g.records[op.ID]
+		}
+		verb := verbs[op.Op]
+		if op.Op == OpReminder && bytes.Contains(raw, []byte(`"reminder":null`)) {
+			verb = "Cancel reminder of"
+		}
+		lines = append(lines, fmt.Sprintf("%s event %q (%s)", verb, eventTitle(raw), op.ID))
+	}
+
+	var msg strings.Builder
+	if len(lines) == 1 {
+		msg.WriteString(lines[0])
+	} else {
+		fmt.Fprintf(&msg, "Change %d events", len(lines))
+	}
+	msg.WriteString("\n")
+	if len(lines) > 1 {
+		msg.WriteString("\n" + strings.Join(lines, "\n") + "\n")
+	}
+	if g.annotation != "" {
+		fmt.Fprintf(&msg, "\nCommand: %s\n", g.annotation)
+	}
+	return msg.String()
+}
+
+func eventTitle(raw json.RawMessage) string {
+	var e struct {
+		Title string `json:"title"`
+	}
+	json.Unmarshal(raw, &e)
+	return e.Title
+}
+
+func signature() *object.Signature {
+	name := "calendarApp"
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		name = u.Username
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "localhost"
+	}
+	return &object.Signature{Name: name, Email: name + "@" + host, When: time.Now()}
+}
+
+// History возвращает изменения события от новых к старым.
+func (g *GitStorage) History(id string) ([]Revision, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if err := g.load(); err != nil {
+		return nil, err
+	}
+	head, err := g.head(g.repo)
+	if err != nil || head == nil {
+		return nil, err
+	}
+	name := eventPath(id)
+	commits, err := g.repo.Log(&git.LogOptions{From: head.Hash, FileName: &name})
+	if err != nil {
+		return nil, err
+	}
+	defer commits.Close()
+
+	var list []Revision
+	err = commits.ForEach(func(c *object.Commit) error {
+		rev := Revision{
+			Hash:    c.Hash.String(),
+			Time:    c.Author.When,
+			Author:  c.Author.Name,
+			Message: strings.TrimSpace(c.Message),
+		}
+		f, err := c.File(name)
+		if err == nil {
+			content, err := f.Contents()
+			if err != nil {
+				return err
+			}
+			var e events.Event
+			if err := json.Unmarshal([]byte(content), &e); err != nil {
+				return fmt.Errorf("%w: %s at %s: %v", ErrCorrupted, name, c.Hash, err)
+			}
+			rev.Event = &e
+		} else if !errors.Is(err, object.ErrFileNotFound) {
+			return err
+		}
+		list = append(list, rev)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Time.After(list[j].Time) })
+	return list, nil
+}
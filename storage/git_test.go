@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGitStorage_CommitsAndHistory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data.git")
+	g := NewGitStorage(dir)
+	e := journalEvent("1", "First")
+	g.Annotate(`add "First"`)
+	if err := g.Put(e); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Put(journalEvent("2", "Second")); err != nil {
+		t.Fatal(err)
+	}
+	g.Annotate("update 1")
+	e.Title = "Renamed"
+	if err := g.Put(e); err != nil {
+		t.Fatal(err)
+	}
+	// Неизменённое событие не создаёт пустой коммит.
+	if err := g.Put(e); err != nil {
+		t.Fatal(err)
+	}
+	g.Annotate("")
+	if err := g.Delete("1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewGitStorage(dir).All(); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked while the repository is open, got %v", err)
+	}
+
+	history, err := g.History("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("Expected 3 revisions of event 1, got %d: %+v", len(history), history)
+	}
+	if history[0].Event != nil || !strings.HasPrefix(history[0].Message, `Delete event "Renamed" (1)`) {
+		t.Errorf("Expected the latest revision to be the deletion, got %+v", history[0])
+	}
+	if history[1].Event == nil || history[1].Event.Title != "Renamed" || !strings.Contains(history[1].Message, "Command: update 1") {
+		t.Errorf("Expected the rename with its command, got %+v", history[1])
+	}
+	if history[2].Event == nil || history[2].Event.Title != "First" {
+		t.Errorf("Expected the first revision to be the added event, got %+v", history[2])
+	}
+	g.Close()
+
+	reopened := NewGitStorage(dir)
+	defer reopened.Close()
+	list, err := reopened.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list["2"].Title != "Second" {
+		t.Errorf("Expected only event 2 after reopening, got %v", list)
+	}
+}
@@ -0,0 +1,260 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/TsSol87/calendarApp/rotate"
+)
+
+// maxHistoryRecord ограничивает длину одной записи истории при чтении.
+const maxHistoryRecord = 1 << 20
+
+// History — история команд: записи только дописываются, а читаются потоком,
+// поэтому история не держится в памяти целиком.
+type History interface {
+	Append(record []byte) error
+	// Scan передаёт fn записи от старых к новым; record действителен только до возврата из fn.
+	Scan(fn func(record []byte) error) error
+	GetFilename() string
+}
+
+// HistoryRewriter переписывает все записи истории, например чтобы зашифровать их.
+type HistoryRewriter interface {
+	Rewrite(fn func(record []byte) ([]byte, error)) error
+}
+
+// HistoryFile хранит историю в формате JSON Lines: одна запись — одна строка.
+// Файл ротируется по размеру, старые части сжимаются gzip и удаляются сверх заданного числа.
+type HistoryFile struct {
+	filename string
+	opt      rotate.Options
+	legacy   Store
+
+	mutex sync.Mutex
+	file  *rotate.File
+}
+
+func NewHistoryFile(filename string, opt rotate.Options) *HistoryFile {
+	return &HistoryFile{filename: filename, opt: opt}
+}
+
+// WithLegacy задаёт хранилище, из которого история прежнего формата переносится при первом открытии,
+// например запись history.json zip-архива.
+func (h *HistoryFile) WithLegacy(s Store) *HistoryFile {
+	h.legacy = s
+	return h
+}
+
+func (h *HistoryFile) GetFilename() string {
+	return h.filename
+}
+
+// BackupFilename возвращает копию истории, оставшуюся после переноса из формата JSON-массива.
+func (h *HistoryFile) BackupFilename() string {
+	return h.filename + BackupSuffix
+}
+
+func (h *HistoryFile) open() error {
+	if h.file != nil {
+		return nil
+	}
+	if err := h.convert(); err != nil {
+		return err
+	}
+	f, err := rotate.Open(h.filename, h.opt)
+	if err != nil {
+		return err
+	}
+	// Строка, оборванная сбоем, закрывается, чтобы следующая запись не склеилась с ней.
+	if data, err := os.ReadFile(h.filename); err == nil && len(data) > 0 && data[len(data)-1] != '\n' {
+		if _, err := f.Write([]byte("\n")); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	h.file = f
+	return nil
+}
+
+// convert переносит историю, записанную одним JSON-массивом, в построчный формат.
+func (h *HistoryFile) convert() error {
+	data, err := os.ReadFile(h.filename)
+	fromLegacy := false
+	if errors.Is(err, os.ErrNotExist) && h.legacy != nil {
+		data, err = h.legacy.Load()
+		fromLegacy = true
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("can't read history: %w", err)
+	}
+	trimmed := bytes.TrimSpace(data)
+	if !bytes.HasPrefix(trimmed, []byte("[")) {
+		if !fromLegacy || len(trimmed) == 0 {
+			return nil
+		}
+		// Зашифрованная история переносится одной записью; её разворачивает EncryptedHistory.
+		return writeFileAtomic(h.filename, append(trimmed, '\n'), 0644)
+	}
+	lines, err := splitArray(trimmed)
+	if err != nil {
+		return fmt.Errorf("%w: history %s: %v", ErrCorrupted, h.filename, err)
+	}
+	return writeFileAtomic(h.filename, lines, 0644)
+}
+
+// splitArray превращает JSON-массив записей в строки JSON Lines.
+func splitArray(data []byte) ([]byte, error) {
+	var records []json.RawMessage
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+	var lines bytes.Buffer
+	for _, r := range records {
+		if err := json.Compact(&lines, r); err != nil {
+			return nil, err
+		}
+		lines.WriteByte('\n')
+	}
+	return lines.Bytes(), nil
+}
+
+func (h *HistoryFile) Append(record []byte) error {
+	var line bytes.Buffer
+	if err := json.Compact(&line, record); err != nil {
+		return fmt.Errorf("history record is not valid JSON: %w", err)
+	}
+	line.WriteByte('\n')
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if err := h.open(); err != nil {
+		return err
+	}
+	_, err := h.file.Write(line.Bytes())
+	return err
+}
+
+func (h *HistoryFile) Scan(fn func(record []byte) error) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if err := h.open(); err != nil {
+		return err
+	}
+	parts, err := rotate.Parts(h.filename)
+	if err != nil {
+		return err
+	}
+	for _, path := range parts {
+		if err := scanPart(path, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanPart(path string, fn func(record []byte) error) error {
+	r, err := rotate.OpenPart(path)
+	if errors.Is(err, os.ErrNotExist) {
+		// Часть удалил по правилам хранения другой сеанс программы.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return scanLines(r, func(line []byte) error {
+		// Запись, оборванную сбоем, пропускаем.
+		if !json.Valid(line) {
+			return nil
+		}
+		return fn(line)
+	})
+}
+
+func scanLines(r io.Reader, fn func(line []byte) error) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxHistoryRecord)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+// Rewrite заменяет каждую запись во всех частях истории результатом fn.
+// fn может вернуть несколько записей, разделённых переводом строки, или nil, чтобы удалить запись.
+// Части переписываются по одной, поэтому в памяти одновременно не больше одной части.
+func (h *HistoryFile) Rewrite(fn func(record []byte) ([]byte, error)) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if err := h.open(); err != nil {
+		return err
+	}
+	if err := h.file.Close(); err != nil {
+		return err
+	}
+	h.file = nil
+	parts, err := rotate.Parts(h.filename)
+	if err != nil {
+		return err
+	}
+	// Сначала переписываются все части в памяти: если fn вернёт ошибку, файлы останутся прежними.
+	rewritten := make([][]byte, len(parts))
+	for i, path := range parts {
+		if rewritten[i], err = rewritePart(path, fn); err != nil {
+			h.open()
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	for i, path := range parts {
+		if err := rotate.WritePart(path, rewritten[i]); err != nil {
+			h.open()
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return h.open()
+}
+
+func rewritePart(path string, fn func(record []byte) ([]byte, error)) ([]byte, error) {
+	var out bytes.Buffer
+	err := scanPart(path, func(record []byte) error {
+		replaced, err := fn(record)
+		if err != nil {
+			return err
+		}
+		for _, line := range bytes.Split(replaced, []byte("\n")) {
+			if line = bytes.TrimSpace(line); len(line) > 0 {
+				out.Write(line)
+				out.WriteByte('\n')
+			}
+		}
+		return nil
+	})
+	return out.Bytes(), err
+}
+
+func (h *HistoryFile) Close() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.file == nil {
+		return nil
+	}
+	err := h.file.Close()
+	h.file = nil
+	return err
+}
@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TsSol87/calendarApp/rotate"
+)
+
+func scanHistory(t *testing.T, h History) []string {
+	t.Helper()
+	var got []string
+	if err := h.Scan(func(record []byte) error {
+		got = append(got, string(record))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestHistoryFile_ConvertsLegacyArrayAndRotates(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "log_data.json")
+	if err := os.WriteFile(filename, []byte(`[{"Message":"add"}, {"Message":"list"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	h := NewHistoryFile(filename, rotate.Options{MaxSize: 40, MaxFiles: 1, Compress: true})
+	defer h.Close()
+
+	for _, msg := range []string{`{"Message":"week"}`, `{"Message":"month"}`, `{"Message":"exit"}`} {
+		if err := h.Append([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backups, _ := rotate.Backups(filename)
+	if len(backups) != 1 || !strings.HasSuffix(backups[0], rotate.GzipSuffix) {
+		t.Errorf("Expected one compressed rotated part, got %v", backups)
+	}
+	got := strings.Join(scanHistory(t, h), " ")
+	if got != `{"Message":"week"} {"Message":"month"} {"Message":"exit"}` {
+		t.Errorf("Expected records kept by retention in order, got %s", got)
+	}
+}
+
+func TestHistoryFile_SkipsTruncatedRecord(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "log_data.json")
+	os.WriteFile(filename, []byte("{\"Message\":\"add\"}\n{\"Mess"), 0644)
+	h := NewHistoryFile(filename, rotate.Options{})
+	defer h.Close()
+	if err := h.Append([]byte(`{"Message":"list"}`)); err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(scanHistory(t, h), " ")
+	if got != `{"Message":"add"} {"Message":"list"}` {
+		t.Errorf("Expected the truncated record to be skipped, got %s", got)
+	}
+}
+
+func TestHistoryFile_ImportsLegacyStore(t *testing.T) {
+	dir := t.TempDir()
+	z := NewZipStorage(filepath.Join(dir, "data.zip"))
+	if err := z.Entry(HistoryEntry).Save([]byte(`[{"Message":"add"}]`)); err != nil {
+		t.Fatal(err)
+	}
+	h := NewHistoryFile(filepath.Join(dir, "log_data.json"), rotate.Options{}).WithLegacy(z.Entry(HistoryEntry))
+	defer h.Close()
+	if got := scanHistory(t, h); len(got) != 1 || got[0] != `{"Message":"add"}` {
+		t.Errorf("Expected history from the zip entry, got %v", got)
+	}
+}
+
+func TestEncryptedHistory_EncryptAndRotate(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "log_data.json")
+	data := NewEncryptedStorage(NewJsonStorage(filepath.Join(dir, "data.json")), Passphrase([]byte("secret"))).WithScryptParams(testScrypt)
+
+	// История, зашифрованная прежде целиком, и запись, сделанная до включения шифрования.
+	whole, err := data.Wrap(NewJsonStorage(filename)).seal([]byte(`[{"Message":"add"},{"Message":"list"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filename, append(whole, []byte("\n{\"Message\":\"week\"}\n")...), 0644)
+
+	plain := NewHistoryFile(filename, rotate.Options{})
+	defer plain.Close()
+	h := data.History(plain)
+	if err := h.Encrypt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Append([]byte(`{"Message":"Acme"}`)); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := os.ReadFile(filename)
+	if bytes.Contains(raw, []byte("week")) || bytes.Contains(raw, []byte("Acme")) {
+		t.Fatalf("Expected every record to be encrypted, got %s", raw)
+	}
+	salts := make(map[string]bool)
+	plain.Scan(func(record []byte) error {
+		var header encryptedHeader
+		json.Unmarshal(record, &header)
+		salts[header.Salt] = true
+		return nil
+	})
+	if len(salts) != 1 {
+		t.Errorf("Expected all records to share one salt, got %d", len(salts))
+	}
+	want := `{"Message":"add"} {"Message":"list"} {"Message":"week"} {"Message":"Acme"}`
+	if got := strings.Join(scanHistory(t, h), " "); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+
+	if err := h.Rotate(Passphrase([]byte("new"))); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(scanHistory(t, h), " "); got != want {
+		t.Errorf("Expected the same records after rotation, got %s", got)
+	}
+	stale := data.History(NewHistoryFile(filename, rotate.Options{}))
+	if err := stale.Scan(func([]byte) error { return nil }); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Expected the old passphrase to fail after rotation, got %v", err)
+	}
+}
+
+func TestEncryptedHistory_Rewrite(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "log_data.json")
+	data := NewEncryptedStorage(NewJsonStorage(filepath.Join(dir, "data.json")), Passphrase([]byte("secret"))).WithScryptParams(testScrypt)
+	plain := NewHistoryFile(filename, rotate.Options{})
+	defer plain.Close()
+	h := data.History(plain)
+	for _, message := range []string{"add", "list"} {
+		if err := h.Append([]byte(`{"Message":"` + message + `"}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := h.Rewrite(func(record []byte) ([]byte, error) {
+		return bytes.Replace(record, []byte(`{"Message"`), []byte(`{"Seq":1,"Message"`), 1), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"Seq":1,"Message":"add"} {"Seq":1,"Message":"list"}`
+	if got := strings.Join(scanHistory(t, h), " "); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+	if raw, _ := os.ReadFile(filename); bytes.Contains(raw, []byte("list")) {
+		t.Errorf("Expected rewritten records to stay encrypted, got %s", raw)
+	}
+}
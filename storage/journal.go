@@ -0,0 +1,505 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/TsSol87/calendarApp/events"
+)
+
+const (
+	JournalSuffix       = ".journal"
+	DefaultCompactEvery = 100
+)
+
+const (
+	OpAdd      = "add"
+	OpUpdate   = "update"
+	OpReminder = "reminder"
+	OpDelete   = "delete"
+)
+
+type JournalOp struct {
+	Op    string          `json:"op"`
+	ID    string          `json:"id"`
+	Event json.RawMessage `json:"event,omitempty"`
+}
+
+// JournalRecord — одна строка журнала: все операции одной транзакции.
+type JournalRecord struct {
+	Seq uint64      `json:"seq"`
+	At  time.Time   `json:"at"`
+	Ops []JournalOp `json:"ops"`
+}
+
+// JournalStorage дописывает каждое изменение в журнал рядом со снимком календаря и сбрасывает его на диск.
+// При загрузке журнал проигрывается поверх снимка, а раз в compactEvery записей сворачивается в новый снимок.
+// Снимок имеет формат JsonStorage, поэтому между бэкендами json и journal можно переключаться.
+// Журнал принадлежит одному процессу: второй сеанс с теми же данными получит ErrLocked.
+type JournalStorage struct {
+	snapshot     *JsonStorage
+	journalPath  string
+	compactEvery int
+
+	mutex   sync.Mutex
+	file    *os.File
+	lock    *FileLock
+	size    int64
+	seq     uint64
+	pending int
+	records map[string]json.RawMessage
+	loaded  bool
+}
+
+func NewJournalStorage(filename string, compactEvery int) *JournalStorage {
+	if compactEvery <= 0 {
+		compactEvery = DefaultCompactEvery
+	}
+	return &JournalStorage{
+		snapshot:     NewJsonStorage(filename),
+		journalPath:  filename + JournalSuffix,
+		compactEvery: compactEvery,
+	}
+}
+
+func (j *JournalStorage) GetFilename() string {
+	return j.snapshot.GetFilename()
+}
+
+func (j *JournalStorage) JournalFilename() string {
+	return j.journalPath
+}
+
+func (j *JournalStorage) BackupFilename() string {
+	return j.snapshot.BackupFilename()
+}
+
+func (j *JournalStorage) Recover() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.loaded = false
+	return j.snapshot.Recover()
+}
+
+// Save заменяет календарь целиком и сразу сворачивает журнал в снимок.
+func (j *JournalStorage) Save(data []byte) error {
+	list, _, err := DecodeCalendar(data)
+	if err != nil {
+		return err
+	}
+	records, err := encodeRecords(list)
+	if err != nil {
+		return err
+	}
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if err := j.load(); err != nil {
+		return err
+	}
+	j.records = records
+	return j.compact()
+}
+
+func (j *JournalStorage) Load() ([]byte, error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if err := j.load(); err != nil {
+		return nil, err
+	}
+	return EncodeCalendar(j.records)
+}
+
+// Migrate обновляет снимок до текущей схемы; записи журнала всегда пишутся в текущем формате события.
+func (j *JournalStorage) Migrate(dryRun bool) (*MigrationReport, error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	data, err := j.snapshot.Load()
+	if errors.Is(err, os.ErrNotExist) {
+		return &MigrationReport{From: SchemaVersion, To: SchemaVersion}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, report, err := DecodeCalendar(data)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun || !report.Pending() {
+		return report, nil
+	}
+	if err := j.load(); err != nil {
+		return nil, err
+	}
+	return report, j.compact()
+}
+
+func (j *JournalStorage) Get(id string) (*events.Event, error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if err := j.load(); err != nil {
+		return nil, err
+	}
+	return decodeEvent(j.records, id)
+}
+
+func (j *JournalStorage) Put(e *events.Event) error {
+	return j.Transaction(func(tx Repository) error { return tx.Put(e) })
+}
+
+func (j *JournalStorage) Delete(id string) error {
+	return j.Transaction(func(tx Repository) error { return tx.Delete(id) })
+}
+
+func (j *JournalStorage) Range(from, to time.Time) ([]*events.Event, error) {
+	list, err := j.All()
+	if err != nil {
+		return nil, err
+	}
+	return rangeEvents(list, from, to), nil
+}
+
+func (j *JournalStorage) All() (map[string]*events.Event, error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if err := j.load(); err != nil {
+		return nil, err
+	}
+	return decodeEvents(j.records)
+}
+
+// Transaction записывает все операции fn одной строкой журнала: при сбое строка либо целая, либо отбрасывается.
+func (j *JournalStorage) Transaction(fn func(tx Repository) error) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if err := j.load(); err != nil {
+		return err
+	}
+
+	tx := &journalTx{records: make(map[string]json.RawMessage, len(j.records))}
+	for id, raw := range j.records {
+		tx.records[id] = raw
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if len(tx.ops) == 0 {
+		return nil
+	}
+
+	record := JournalRecord{Seq: j.seq + 1, At: time.Now().UTC(), Ops: tx.ops}
+	if err := j.append(record); err != nil {
+		return err
+	}
+	j.seq = record.Seq
+	j.records = tx.records
+	j.pending++
+	if j.pending >= j.compactEvery {
+		return j.compact()
+	}
+	return nil
+}
+
+// Close сворачивает журнал в снимок и закрывает файл журнала.
+func (j *JournalStorage) Close() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	var err error
+	if j.loaded && j.pending > 0 {
+		err = j.compact()
+	}
+	if j.file != nil {
+		err = errors.Join(err, j.file.Close())
+		j.file = nil
+	}
+	err = errors.Join(err, j.lock.Unlock())
+	j.lock = nil
+	return err
+}
+
+// Compact переносит содержимое журнала в снимок.
+func (j *JournalStorage) Compact() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if err := j.load(); err != nil {
+		return err
+	}
+	return j.compact()
+}
+
+func (j *JournalStorage) compact() error {
+	data, err := EncodeCalendar(j.records)
+	if err != nil {
+		return err
+	}
+	if err := j.snapshot.Save(data); err != nil {
+		return fmt.Errorf("can't write snapshot: %w", err)
+	}
+	// Если процесс упадёт до усечения, журнал проиграется повторно: операции идемпотентны.
+	if err := j.file.Truncate(0); err != nil {
+		return fmt.Errorf("can't truncate journal: %w", err)
+	}
+	if err := j.file.Sync(); err != nil {
+		return err
+	}
+	j.size = 0
+	j.pending = 0
+	return nil
+}
+
+func (j *JournalStorage) append(record JournalRecord) error {
+	line, err := encodeRecord(record)
+	if err != nil {
+		return err
+	}
+	if _, err := j.file.Write(line); err != nil {
+		j.file.Truncate(j.size)
+		return fmt.Errorf("can't append to journal: %w", err)
+	}
+	if err := j.file.Sync(); err != nil {
+		j.file.Truncate(j.size)
+		return fmt.Errorf("can't sync journal: %w", err)
+	}
+	j.size += int64(len(line))
+	return nil
+}
+
+func (j *JournalStorage) load() error {
+	if j.loaded {
+		return nil
+	}
+	if j.lock == nil {
+		l, err := TryLockFile(j.GetFilename(), true)
+		if err != nil {
+			return err
+		}
+		j.lock = l
+	}
+	records := make(map[string]json.RawMessage)
+	data, err := j.snapshot.Load()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err == nil {
+		list, _, err := DecodeCalendar(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", j.GetFilename(), err)
+		}
+		if records, err = encodeRecords(list); err != nil {
+			return err
+		}
+	}
+
+	if j.file == nil {
+		f, err := os.OpenFile(j.journalPath, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("can't open journal: %w", err)
+		}
+		j.file = f
+	}
+	if _, err := j.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	journal, err := io.ReadAll(j.file)
+	if err != nil {
+		return fmt.Errorf("can't read journal: %w", err)
+	}
+
+	list, valid, err := ReplayJournal(journal)
+	if err != nil {
+		return fmt.Errorf("%s: %w", j.journalPath, err)
+	}
+	if valid < int64(len(journal)) {
+		// Последняя запись оборвана сбоем: она не была подтверждена, отбрасываем её.
+		if err := j.file.Truncate(valid); err != nil {
+			return fmt.Errorf("can't drop torn journal record: %w", err)
+		}
+	}
+	for _, record := range list {
+		applyRecord(records, record)
+		j.seq = record.Seq
+	}
+
+	j.records = records
+	j.size = valid
+	j.pending = len(list)
+	j.loaded = true
+	return nil
+}
+
+// ReplayJournal разбирает журнал и возвращает записи и длину его целой части.
+// Оборванная последняя строка не считается ошибкой; повреждение в середине — ErrCorrupted.
+func ReplayJournal(data []byte) ([]JournalRecord, int64, error) {
+	var list []JournalRecord
+	var offset int64
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			return list, offset, nil
+		}
+		record, err := decodeRecord(data[:end])
+		if err != nil {
+			if end == len(data)-1 {
+				return list, offset, nil
+			}
+			return nil, 0, fmt.Errorf("%w: journal record at offset %d: %v", ErrCorrupted, offset, err)
+		}
+		list = append(list, record)
+		offset += int64(end + 1)
+		data = data[end+1:]
+	}
+	return list, offset, nil
+}
+
+// encodeRecord формирует строку "<crc32> <json>\n"; контрольная сумма отличает оборванную запись от целой.
+func encodeRecord(record JournalRecord) ([]byte, error) {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	line := fmt.Sprintf("%08x %s\n", crc32.ChecksumIEEE(payload), payload)
+	return []byte(line), nil
+}
+
+func decodeRecord(line []byte) (JournalRecord, error) {
+	var record JournalRecord
+	sum, payload, ok := bytes.Cut(line, []byte(" "))
+	if !ok {
+		return record, errors.New("missing checksum")
+	}
+	want, err := strconv.ParseUint(string(sum), 16, 32)
+	if err != nil {
+		return record, fmt.Errorf("invalid checksum: %w", err)
+	}
+	if crc32.ChecksumIEEE(payload) != uint32(want) {
+		return record, errors.New("checksum mismatch")
+	}
+	if err := json.Unmarshal(payload, &record); err != nil {
+		return record, err
+	}
+	return record, nil
+}
+
+func applyRecord(records map[string]json.RawMessage, record JournalRecord) {
+	for _, op := range record.Ops {
+		if op.Op == OpDelete {
+			delete(records, op.ID)
+			continue
+		}
+		records[op.ID] = op.Event
+	}
+}
+
+// journalTx копит операции транзакции JournalStorage поверх копии записей.
+type journalTx struct {
+	records map[string]json.RawMessage
+	ops     []JournalOp
+}
+
+func (tx *journalTx) Get(id string) (*events.Event, error) {
+	return decodeEvent(tx.records, id)
+}
+
+func (tx *journalTx) Put(e *events.Event) error {
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	prev, exists := tx.records[e.ID]
+	if exists && bytes.Equal(prev, raw) {
+		return nil
+	}
+	op := OpAdd
+	if exists {
+		op = OpUpdate
+		if onlyReminderChanged(prev, e) {
+			op = OpReminder
+		}
+	}
+	tx.records[e.ID] = raw
+	tx.ops = append(tx.ops, JournalOp{Op: op, ID: e.ID, Event: raw})
+	return nil
+}
+
+func (tx *journalTx) Delete(id string) error {
+	if _, ok := tx.records[id]; !ok {
+		return fmt.Errorf("%w: %q", ErrNotFound, id)
+	}
+	delete(tx.records, id)
+	tx.ops = append(tx.ops, JournalOp{Op: OpDelete, ID: id})
+	return nil
+}
+
+func (tx *journalTx) Range(from, to time.Time) ([]*events.Event, error) {
+	list, err := decodeEvents(tx.records)
+	if err != nil {
+		return nil, err
+	}
+	return rangeEvents(list, from, to), nil
+}
+
+func (tx *journalTx) All() (map[string]*events.Event, error) {
+	return decodeEvents(tx.records)
+}
+
+func (tx *journalTx) Transaction(fn func(tx Repository) error) error {
+	return fn(tx)
+}
+
+func onlyReminderChanged(prev json.RawMessage, e *events.Event) bool {
+	var old events.Event
+	if err := json.Unmarshal(prev, &old); err != nil {
+		return false
+	}
+	old.Reminder = nil
+	current := *e
+	current.Reminder = nil
+	a, errA := json.Marshal(old)
+	b, errB := json.Marshal(current)
+	return errA == nil && errB == nil && bytes.Equal(a, b)
+}
+
+func encodeRecords(list map[string]*events.Event) (map[string]json.RawMessage, error) {
+	records := make(map[string]json.RawMessage, len(list))
+	for id, e := range list {
+		raw, err := json.Marshal(e)
+		if err != nil {
+			return nil, err
+		}
+		records[id] = raw
+	}
+	return records, nil
+}
+
+func decodeEvent(records map[string]json.RawMessage, id string) (*events.Event, error) {
+	raw, ok := records[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrNotFound, id)
+	}
+	var e events.Event
+	if err := json.Unmarshal(raw, &e); err != nil {
+		return nil, fmt.Errorf("%w: event %s: %v", ErrCorrupted, id, err)
+	}
+	return &e, nil
+}
+
+func decodeEvents(records map[string]json.RawMessage) (map[string]*events.Event, error) {
+	list := make(map[string]*events.Event, len(records))
+	for id := range records {
+		e, err := decodeEvent(records, id)
+		if err != nil {
+			return nil, err
+		}
+		list[id] = e
+	}
+	return list, nil
+}
+
+var _ Repository = (*JournalStorage)(nil)
@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TsSol87/calendarApp/events"
+	"github.com/TsSol87/calendarApp/priority"
+	"github.com/TsSol87/calendarApp/reminder"
+)
+
+func journalEvent(id, title string) *events.Event {
+	return &events.Event{ID: id, Title: title, StartAt: time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC), Priority: priority.PriorityLow}
+}
+
+func TestJournalStorage_ReplayAndOps(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data.json")
+	j := NewJournalStorage(filename, 100)
+	e := journalEvent("1", "First")
+	steps := []func() error{
+		func() error { return j.Put(e) },
+		func() error { e.Title = "Renamed"; return j.Put(e) },
+		func() error { e.Reminder = &reminder.Reminder{Message: "ping", At: e.StartAt}; return j.Put(e) },
+		func() error { return j.Put(journalEvent("2", "Second")) },
+		func() error { return j.Delete("2") },
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(j.JournalFilename())
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, _, err := ReplayJournal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ops []string
+	for _, r := range records {
+		for _, op := range r.Ops {
+			ops = append(ops, op.Op)
+		}
+	}
+	want := []string{OpAdd, OpUpdate, OpReminder, OpAdd, OpDelete}
+	if len(ops) != len(want) {
+		t.Fatalf("Expected ops %v, got %v", want, ops)
+	}
+	for i := range want {
+		if ops[i] != want[i] {
+			t.Errorf("Expected ops %v, got %v", want, ops)
+			break
+		}
+	}
+
+	if _, err := NewJournalStorage(filename, 100).All(); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked while the journal is open in another session, got %v", err)
+	}
+	j.Close()
+
+	list, err := NewJournalStorage(filename, 100).All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list["1"].Title != "Renamed" || list["1"].Reminder == nil {
+		t.Errorf("Unexpected state after replay: %+v", list)
+	}
+}
+
+func TestJournalStorage_TornLastRecord(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data.json")
+	j := NewJournalStorage(filename, 100)
+	if err := j.Put(journalEvent("1", "First")); err != nil {
+		t.Fatal(err)
+	}
+	j.Close()
+	good, _ := os.ReadFile(filename + JournalSuffix)
+
+	f, err := os.OpenFile(filename+JournalSuffix, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte(`1234abcd {"seq":2,"ops":[{"op":"add","id":"2","eve`))
+	f.Close()
+
+	reopened := NewJournalStorage(filename, 100)
+	list, err := reopened.All()
+	if err != nil {
+		t.Fatalf("Expected torn record to be ignored, got %v", err)
+	}
+	if len(list) != 1 {
+		t.Errorf("Expected only the committed event, got %d", len(list))
+	}
+	data, _ := os.ReadFile(filename + JournalSuffix)
+	if len(data) != len(good) {
+		t.Errorf("Expected torn tail to be truncated to %d bytes, got %d", len(good), len(data))
+	}
+	if err := reopened.Put(journalEvent("3", "Third")); err != nil {
+		t.Fatal(err)
+	}
+	reopened.Close()
+	if list, err := NewJournalStorage(filename, 100).All(); err != nil || len(list) != 2 {
+		t.Errorf("Expected journal to stay readable after append, got %d events (%v)", len(list), err)
+	}
+}
+
+func TestJournalStorage_CorruptedMiddleRecord(t *testing.T) {
+	data := []byte("00000000 {}\n" + "00000000 {}\n")
+	if _, _, err := ReplayJournal(data); !errors.Is(err, ErrCorrupted) {
+		t.Errorf("Expected ErrCorrupted for damaged record in the middle, got %v", err)
+	}
+}
+
+func TestJournalStorage_Compaction(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data.json")
+	j := NewJournalStorage(filename, 2)
+	for _, id := range []string{"1", "2", "3"} {
+		if err := j.Put(journalEvent(id, "Event "+id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snapshot, err := NewJsonStorage(filename).Load()
+	if err != nil {
+		t.Fatalf("Expected snapshot after compaction, got %v", err)
+	}
+	data, _ := os.ReadFile(j.JournalFilename())
+	records, _, _ := ReplayJournal(data)
+	if len(records) != 1 {
+		t.Errorf("Expected one record after compaction, got %d (snapshot %s)", len(records), snapshot)
+	}
+
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(j.JournalFilename()); len(data) != 0 {
+		t.Errorf("Expected empty journal after Close, got %q", data)
+	}
+	list, err := NewRepository(NewJsonStorage(filename)).All()
+	if err != nil || len(list) != 3 {
+		t.Errorf("Expected json backend to read the snapshot, got %d events (%v)", len(list), err)
+	}
+}
@@ -1,7 +1,10 @@
 package storage
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
+	"time"
 )
 
 type JsonStorage struct {
@@ -14,7 +17,7 @@ func NewJsonStorage(filename string) *JsonStorage {
 	}
 }
 func (s *JsonStorage) Save(data []byte) error {
-	err := os.WriteFile(s.GetFilename(), data, 0644)
+	err := writeFileAtomic(s.GetFilename(), data, 0644)
 	if err != nil {
 		return err
 	}
@@ -26,5 +29,28 @@ func (s *JsonStorage) Load() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("%w: %s contains truncated or invalid JSON", ErrCorrupted, s.GetFilename())
+	}
 	return data, nil
 }
+
+func (s *JsonStorage) BackupFilename() string {
+	return s.GetFilename() + BackupSuffix
+}
+
+// Recover восстанавливает файл из резервной копии; повреждённый файл сохраняется рядом для разбора.
+func (s *JsonStorage) Recover() error {
+	data, err := os.ReadFile(s.BackupFilename())
+	if err != nil {
+		return fmt.Errorf("can't read backup: %w", err)
+	}
+	if !json.Valid(data) {
+		return fmt.Errorf("%w: backup %s is also invalid", ErrCorrupted, s.BackupFilename())
+	}
+	corrupted := fmt.Sprintf("%s.corrupt-%s", s.GetFilename(), time.Now().Format("20060102-150405"))
+	if err := os.Rename(s.GetFilename(), corrupted); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return writeFileAtomic(s.GetFilename(), data, 0644)
+}
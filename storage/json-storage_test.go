@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJsonStorage_SaveKeepsBackup(t *testing.T) {
+	s := NewJsonStorage(filepath.Join(t.TempDir(), "data.json"))
+	if err := s.Save([]byte(`{"v":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save([]byte(`{"v":2}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := s.Load()
+	if err != nil || string(data) != `{"v":2}` {
+		t.Errorf("Expected latest data, got %q (%v)", data, err)
+	}
+	bak, err := os.ReadFile(s.BackupFilename())
+	if err != nil || string(bak) != `{"v":1}` {
+		t.Errorf("Expected previous version in backup, got %q (%v)", bak, err)
+	}
+
+	matches, _ := filepath.Glob(s.GetFilename() + ".tmp-*")
+	if len(matches) != 0 {
+		t.Errorf("Expected no temporary files left, got %v", matches)
+	}
+}
+
+func TestJsonStorage_TruncatedFileAndRecover(t *testing.T) {
+	s := NewJsonStorage(filepath.Join(t.TempDir(), "data.json"))
+	if err := s.Save([]byte(`{"v":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save([]byte(`{"v":2}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(s.GetFilename(), []byte(`{"v":`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Load(); !errors.Is(err, ErrCorrupted) {
+		t.Fatalf("Expected ErrCorrupted for truncated file, got %v", err)
+	}
+	if err := s.Recover(); err != nil {
+		t.Fatalf("Expected recovery from backup, got %v", err)
+	}
+	data, err := s.Load()
+	if err != nil || string(data) != `{"v":1}` {
+		t.Errorf("Expected data from backup, got %q (%v)", data, err)
+	}
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	LockSuffix  = ".lock"
+	LockTimeout = 5 * time.Second
+)
+
+var ErrLocked = errors.New("data file is locked by another process")
+
+// FileLock — рекомендательная блокировка файла между процессами; снимается Unlock.
+type FileLock struct {
+	file *os.File
+}
+
+// LockFile ждёт блокировку filename+".lock" не дольше LockTimeout.
+// exclusive нужна для записи, разделяемая — для чтения.
+func LockFile(filename string, exclusive bool) (*FileLock, error) {
+	return lockFile(filename, exclusive, LockTimeout)
+}
+
+// TryLockFile берёт блокировку без ожидания.
+func TryLockFile(filename string, exclusive bool) (*FileLock, error) {
+	return lockFile(filename, exclusive, 0)
+}
+
+func lockFile(filename string, exclusive bool, timeout time.Duration) (*FileLock, error) {
+	f, err := os.OpenFile(filename+LockSuffix, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("can't open lock file: %w", err)
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		locked, err := tryLock(f, exclusive)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("can't lock %s: %w", filename, err)
+		}
+		if locked {
+			return &FileLock{file: f}, nil
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("%w: %s", ErrLocked, filename)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func (l *FileLock) Unlock() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	unlock(l.file)
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
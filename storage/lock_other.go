@@ -0,0 +1,13 @@
+//go:build !unix
+
+package storage
+
+import "os"
+
+// На системах без flock блокировка не выполняется; изменения других процессов всё равно
+// обнаруживаются по содержимому файла перед записью.
+func tryLock(f *os.File, exclusive bool) (bool, error) {
+	return true, nil
+}
+
+func unlock(f *os.File) {}
@@ -0,0 +1,31 @@
+//go:build unix
+
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestLockFile_Exclusive(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data.json")
+	l, err := TryLockFile(filename, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shared, err := TryLockFile(filename, false); err != nil {
+		t.Errorf("Expected shared locks to coexist, got %v", err)
+	} else {
+		shared.Unlock()
+	}
+	if _, err := TryLockFile(filename, true); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked while a shared lock is held, got %v", err)
+	}
+	l.Unlock()
+	exclusive, err := TryLockFile(filename, true)
+	if err != nil {
+		t.Fatalf("Expected lock after release, got %v", err)
+	}
+	exclusive.Unlock()
+}
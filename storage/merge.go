@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/TsSol87/calendarApp/events"
+)
+
+var ErrConflict = errors.New("data was changed by another process")
+
+// ConflictError перечисляет события, изменённые и в этом процессе, и в другом.
+type ConflictError struct {
+	IDs []string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%v: conflicting changes to events %s", ErrConflict, strings.Join(e.IDs, ", "))
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// merge совмещает наши изменения с изменениями другого процесса относительно общей базы.
+// Событие, изменённое только одной стороной, берётся у неё; изменённое обеими по-разному — конфликт.
+func merge(base map[string][]byte, ours, theirs map[string]*events.Event) (map[string]*events.Event, error) {
+	ids := make(map[string]bool)
+	for _, list := range []map[string]*events.Event{ours, theirs} {
+		for id := range list {
+			ids[id] = true
+		}
+	}
+	for id := range base {
+		ids[id] = true
+	}
+
+	result := make(map[string]*events.Event)
+	var conflicts []string
+	for id := range ids {
+		b := base[id]
+		o, err := encodeEvent(ours[id])
+		if err != nil {
+			return nil, err
+		}
+		t, err := encodeEvent(theirs[id])
+		if err != nil {
+			return nil, err
+		}
+
+		var winner *events.Event
+		switch {
+		case bytes.Equal(o, b):
+			winner = theirs[id]
+		case bytes.Equal(t, b), bytes.Equal(o, t):
+			winner = ours[id]
+		default:
+			conflicts = append(conflicts, id)
+			continue
+		}
+		if winner != nil {
+			result[id] = winner
+		}
+	}
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return nil, &ConflictError{IDs: conflicts}
+	}
+	return result, nil
+}
+
+func encodeEvent(e *events.Event) ([]byte, error) {
+	if e == nil {
+		return nil, nil
+	}
+	return json.Marshal(e)
+}
+
+func encodeBase(list map[string]*events.Event) (map[string][]byte, error) {
+	base := make(map[string][]byte, len(list))
+	for id, e := range list {
+		data, err := encodeEvent(e)
+		if err != nil {
+			return nil, err
+		}
+		base[id] = data
+	}
+	return base, nil
+}
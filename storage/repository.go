@@ -0,0 +1,385 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/TsSol87/calendarApp/events"
+)
+
+var ErrNotFound = errors.New("event not found")
+
+// Repository хранит события по одному: календарь работает с ним, а не с сырыми байтами Store.
+type Repository interface {
+	Get(id string) (*events.Event, error)
+	Put(e *events.Event) error
+	Delete(id string) error
+	// Range возвращает события с началом в [from, to), отсортированные по времени.
+	Range(from, to time.Time) ([]*events.Event, error)
+	All() (map[string]*events.Event, error)
+	// Transaction применяет изменения fn целиком или не применяет вовсе.
+	Transaction(fn func(tx Repository) error) error
+}
+
+// NewRepository возвращает хранилище как Repository; файловые хранилища оборачиваются в BlobRepository.
+func NewRepository(s Store) Repository {
+	if r, ok := s.(Repository); ok {
+		return r
+	}
+	return NewBlobRepository(s)
+}
+
+// Refresher сообщает, что данные изменил другой процесс, и подгружает их.
+type Refresher interface {
+	Refresh() (bool, error)
+}
+
+// BlobRepository — адаптер для хранилищ, которые умеют сохранять только календарь целиком
+// (JsonStorage, ZipStorage). События держатся в памяти, каждое изменение переписывает файл.
+// Запись идёт под блокировкой файла; если файл успели изменить в другом процессе,
+// изменения совмещаются, а при конфликте возвращается ErrConflict.
+type BlobRepository struct {
+	store  Store
+	mutex  sync.Mutex
+	list   map[string]*events.Event
+	loaded bool
+
+	// base — события в том виде, в каком они последний раз были прочитаны или записаны.
+	base     map[string][]byte
+	state    fileState
+	reloaded bool
+}
+
+type fileState struct {
+	exists  bool
+	modTime time.Time
+	size    int64
+	hash    [sha256.Size]byte
+	// checked — когда снято состояние; запись в тот же момент может не изменить время файла.
+	checked time.Time
+}
+
+// racyWindow — насколько время изменения файла должно отставать от проверки, чтобы ему можно было доверять
+// без сравнения содержимого (у некоторых файловых систем время хранится с точностью до секунды).
+const racyWindow = 2 * time.Second
+
+func NewBlobRepository(s Store) *BlobRepository {
+	return &BlobRepository{store: s}
+}
+
+func (r *BlobRepository) Store() Store {
+	return r.store
+}
+
+func (r *BlobRepository) lock(exclusive bool) (*FileLock, error) {
+	return LockFile(r.store.GetFilename(), exclusive)
+}
+
+func (r *BlobRepository) load() error {
+	if r.loaded {
+		return nil
+	}
+	l, err := r.lock(false)
+	if err != nil {
+		return err
+	}
+	defer l.Unlock()
+
+	state, data, err := r.read()
+	if err != nil {
+		return err
+	}
+	list, err := r.decode(data)
+	if err != nil {
+		return err
+	}
+	return r.adopt(list, state)
+}
+
+// read читает файл и запоминает его состояние; отсутствующий файл — пустой календарь.
+func (r *BlobRepository) read() (fileState, []byte, error) {
+	state := r.stat()
+	data, err := r.store.Load()
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil, nil
+	}
+	if err != nil {
+		return state, nil, err
+	}
+	state.hash = sha256.Sum256(data)
+	return state, data, nil
+}
+
+func (r *BlobRepository) stat() fileState {
+	info, err := os.Stat(r.store.GetFilename())
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{exists: true, modTime: info.ModTime(), size: info.Size(), checked: time.Now()}
+}
+
+// changed проверяет, изменился ли файл с последнего чтения или записи: сначала по времени и размеру,
+// затем по содержимому. Возвращает новое содержимое, если оно другое.
+func (r *BlobRepository) changed() (bool, fileState, []byte, error) {
+	current := r.stat()
+	if current.exists == r.state.exists && current.modTime.Equal(r.state.modTime) && current.size == r.state.size &&
+		(!current.exists || r.state.checked.Sub(r.state.modTime) > racyWindow) {
+		return false, r.state, nil, nil
+	}
+	state, data, err := r.read()
+	if err != nil {
+		return false, state, nil, err
+	}
+	if state.hash == r.state.hash && state.exists == r.state.exists {
+		r.state = state
+		return false, state, nil, nil
+	}
+	return true, state, data, nil
+}
+
+func (r *BlobRepository) decode(data []byte) (map[string]*events.Event, error) {
+	if data == nil {
+		return make(map[string]*events.Event), nil
+	}
+	// Старые версии обновляются в памяти; файл будет переписан при следующем сохранении или командой migrate.
+	list, _, err := DecodeCalendar(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", r.store.GetFilename(), err)
+	}
+	return list, nil
+}
+
+func (r *BlobRepository) adopt(list map[string]*events.Event, state fileState) error {
+	base, err := encodeBase(list)
+	if err != nil {
+		return err
+	}
+	r.list = list
+	r.base = base
+	r.state = state
+	r.loaded = true
+	return nil
+}
+
+func (r *BlobRepository) flush(list map[string]*events.Event) error {
+	data, err := EncodeCalendar(list)
+	if err != nil {
+		return err
+	}
+	if err := r.store.Save(data); err != nil {
+		return err
+	}
+	state := r.stat()
+	state.hash = sha256.Sum256(data)
+	return r.adopt(list, state)
+}
+
+// Refresh перечитывает файл, если его изменил другой процесс, и сообщает, что события нужно взять заново.
+func (r *BlobRepository) Refresh() (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !r.loaded {
+		return false, r.load()
+	}
+	l, err := r.lock(false)
+	if err != nil {
+		return false, err
+	}
+	defer l.Unlock()
+
+	changed, state, data, err := r.changed()
+	if err != nil {
+		return false, err
+	}
+	if changed {
+		list, err := r.decode(data)
+		if err != nil {
+			return false, err
+		}
+		if err := r.adopt(list, state); err != nil {
+			return false, err
+		}
+	}
+	reloaded := changed || r.reloaded
+	r.reloaded = false
+	return reloaded, nil
+}
+
+func (r *BlobRepository) Migrate(dryRun bool) (*MigrationReport, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	data, err := r.store.Load()
+	if errors.Is(err, os.ErrNotExist) {
+		return &MigrationReport{From: SchemaVersion, To: SchemaVersion}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, report, err := DecodeCalendar(data)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun || !report.Pending() {
+		return report, nil
+	}
+	return report, r.write(func(tx Repository) error { return nil }, true)
+}
+
+func (r *BlobRepository) Get(id string) (*events.Event, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return getEvent(r.list, id)
+}
+
+func (r *BlobRepository) Put(e *events.Event) error {
+	return r.Transaction(func(tx Repository) error { return tx.Put(e) })
+}
+
+func (r *BlobRepository) Delete(id string) error {
+	return r.Transaction(func(tx Repository) error { return tx.Delete(id) })
+}
+
+func (r *BlobRepository) Range(from, to time.Time) ([]*events.Event, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return rangeEvents(r.list, from, to), nil
+}
+
+func (r *BlobRepository) All() (map[string]*events.Event, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return copyEvents(r.list), nil
+}
+
+// Transaction работает с копией набора событий и записывает файл один раз, если fn завершилась без ошибки.
+func (r *BlobRepository) Transaction(fn func(tx Repository) error) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.write(fn, false)
+}
+
+func (r *BlobRepository) write(fn func(tx Repository) error, force bool) error {
+	if err := r.load(); err != nil {
+		return err
+	}
+	l, err := r.lock(true)
+	if err != nil {
+		return err
+	}
+	defer l.Unlock()
+
+	tx := memoryTx(copyEvents(r.list))
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	changed, state, data, err := r.changed()
+	if err != nil {
+		return err
+	}
+	result := map[string]*events.Event(tx)
+	if changed {
+		theirs, err := r.decode(data)
+		if err != nil {
+			return err
+		}
+		result, err = merge(r.base, tx, theirs)
+		if err != nil {
+			// Наши изменения отбрасываются: в памяти остаётся версия другого процесса.
+			r.adopt(theirs, state)
+			r.reloaded = true
+			return err
+		}
+		r.reloaded = true
+	} else if !force && len(tx) == len(r.list) && r.unchanged(tx) {
+		return nil
+	}
+	return r.flush(result)
+}
+
+// unchanged сообщает, совпадают ли события транзакции с последней записанной версией.
+func (r *BlobRepository) unchanged(list map[string]*events.Event) bool {
+	for id, e := range list {
+		data, err := encodeEvent(e)
+		if err != nil || !bytes.Equal(data, r.base[id]) {
+			return false
+		}
+	}
+	return len(list) == len(r.base)
+}
+
+// memoryTx — набор событий внутри транзакции BlobRepository.
+type memoryTx map[string]*events.Event
+
+func (m memoryTx) Get(id string) (*events.Event, error) {
+	return getEvent(m, id)
+}
+
+func (m memoryTx) Put(e *events.Event) error {
+	m[e.ID] = e
+	return nil
+}
+
+func (m memoryTx) Delete(id string) error {
+	if _, ok := m[id]; !ok {
+		return fmt.Errorf("%w: %q", ErrNotFound, id)
+	}
+	delete(m, id)
+	return nil
+}
+
+func (m memoryTx) Range(from, to time.Time) ([]*events.Event, error) {
+	return rangeEvents(m, from, to), nil
+}
+
+func (m memoryTx) All() (map[string]*events.Event, error) {
+	return copyEvents(m), nil
+}
+
+func (m memoryTx) Transaction(fn func(tx Repository) error) error {
+	return fn(m)
+}
+
+func getEvent(list map[string]*events.Event, id string) (*events.Event, error) {
+	e, ok := list[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrNotFound, id)
+	}
+	return e, nil
+}
+
+func rangeEvents(list map[string]*events.Event, from, to time.Time) []*events.Event {
+	result := make([]*events.Event, 0)
+	for _, e := range list {
+		if !e.StartAt.Before(from) && e.StartAt.Before(to) {
+			result = append(result, e)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StartAt.Before(result[j].StartAt)
+	})
+	return result
+}
+
+func copyEvents(list map[string]*events.Event) map[string]*events.Event {
+	result := make(map[string]*events.Event, len(list))
+	for id, e := range list {
+		result[id] = e
+	}
+	return result
+}
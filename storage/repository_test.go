@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TsSol87/calendarApp/events"
+	"github.com/TsSol87/calendarApp/priority"
+)
+
+func repositories(t *testing.T) map[string]Repository {
+	dir := t.TempDir()
+	g := NewGitStorage(filepath.Join(dir, "data.git"))
+	t.Cleanup(func() { g.Close() })
+	return map[string]Repository{
+		"git":    g,
+		"json":   NewRepository(NewJsonStorage(filepath.Join(dir, "data.json"))),
+		"zip":    NewRepository(NewZipStorage(filepath.Join(dir, "data.zip"))),
+		"sqlite": NewRepository(openSQLite(t, filepath.Join(dir, "data.db"))),
+	}
+}
+
+func TestRepository_RangeAndTransaction(t *testing.T) {
+	base := time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC)
+	for name, r := range repositories(t) {
+		t.Run(name, func(t *testing.T) {
+			for i, id := range []string{"c", "a", "b"} {
+				e := &events.Event{ID: id, Title: "Event " + id, StartAt: base.Add(time.Duration(2-i) * time.Hour), Priority: priority.PriorityLow}
+				if err := r.Put(e); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			found, err := r.Range(base, base.Add(2*time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(found) != 2 || found[0].ID != "b" || found[1].ID != "a" {
+				t.Errorf("Expected [b a] ordered by time, got %v", ids(found))
+			}
+
+			failed := errors.New("abort")
+			err = r.Transaction(func(tx Repository) error {
+				if err := tx.Delete("a"); err != nil {
+					return err
+				}
+				return failed
+			})
+			if !errors.Is(err, failed) {
+				t.Fatalf("Expected transaction error, got %v", err)
+			}
+			if _, err := r.Get("a"); err != nil {
+				t.Errorf("Expected rolled back delete to keep event, got %v", err)
+			}
+
+			if err := r.Delete("a"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := r.Get("a"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Expected ErrNotFound after delete, got %v", err)
+			}
+			if err := r.Delete("a"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Expected ErrNotFound deleting twice, got %v", err)
+			}
+		})
+	}
+}
+
+func ids(list []*events.Event) []string {
+	result := make([]string, 0, len(list))
+	for _, e := range list {
+		result = append(result, e.ID)
+	}
+	return result
+}
+
+func TestBlobRepository_ConcurrentSessions(t *testing.T) {
+	base := time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC)
+	event := func(id, title string) *events.Event {
+		return &events.Event{ID: id, Title: title, StartAt: base, Priority: priority.PriorityLow}
+	}
+	for name, open := range map[string]func(string) Store{
+		"json": func(dir string) Store { return NewJsonStorage(filepath.Join(dir, "data.json")) },
+		"zip":  func(dir string) Store { return NewZipStorage(filepath.Join(dir, "data.zip")) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			first, second := NewBlobRepository(open(dir)), NewBlobRepository(open(dir))
+			for _, id := range []string{"a", "b"} {
+				if err := first.Put(event(id, "Event "+id)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := second.All(); err != nil {
+				t.Fatal(err)
+			}
+
+			// Разные события в двух сеансах совмещаются.
+			if err := first.Put(event("a", "Changed in first")); err != nil {
+				t.Fatal(err)
+			}
+			if err := second.Delete("b"); err != nil {
+				t.Fatal(err)
+			}
+			list, err := NewBlobRepository(open(dir)).All()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(list) != 1 || list["a"].Title != "Changed in first" {
+				t.Errorf("Expected both changes to be kept, got %v", list)
+			}
+			if changed, err := second.Refresh(); err != nil || !changed {
+				t.Errorf("Expected Refresh to report merged changes, got %v (%v)", changed, err)
+			}
+
+			// Одно и то же событие, изменённое в обоих сеансах, — конфликт, файл не перезаписывается.
+			if _, err := first.Refresh(); err != nil {
+				t.Fatal(err)
+			}
+			if err := first.Put(event("a", "First wins")); err != nil {
+				t.Fatal(err)
+			}
+			err = second.Put(event("a", "Second loses"))
+			var conflict *ConflictError
+			if !errors.Is(err, ErrConflict) || !errors.As(err, &conflict) || len(conflict.IDs) != 1 || conflict.IDs[0] != "a" {
+				t.Fatalf("Expected conflict on event a, got %v", err)
+			}
+			if e, err := second.Get("a"); err != nil || e.Title != "First wins" {
+				t.Errorf("Expected conflicting session to reload the other version, got %v (%v)", e, err)
+			}
+			if e, err := NewBlobRepository(open(dir)).Get("a"); err != nil || e.Title != "First wins" {
+				t.Errorf("Expected file to keep the first version, got %v (%v)", e, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/TsSol87/calendarApp/events"
+)
+
+// SchemaVersion — версия формата файла календаря, который пишет текущая версия программы.
+const SchemaVersion = 2
+
+// Envelope — сохраняемый документ календаря: версия схемы и события по ID.
+type Envelope[T any] struct {
+	SchemaVersion int          `json:"schema_version"`
+	Events        map[string]T `json:"events"`
+}
+
+// MigrationReport описывает, какие шаги нужны, чтобы довести документ до текущей версии.
+type MigrationReport struct {
+	From   int
+	To     int
+	Steps  []string
+	Events int
+}
+
+func (r *MigrationReport) Pending() bool {
+	return r.From < r.To
+}
+
+// Migrator обновляет данные хранилища до текущей схемы; при dryRun только сообщает, что будет сделано.
+type Migrator interface {
+	Migrate(dryRun bool) (*MigrationReport, error)
+}
+
+type schemaMigration struct {
+	from        int
+	description string
+	apply       func(doc map[string]any) (map[string]any, error)
+}
+
+// schemaMigrations[i] переводит документ из версии i в i+1 и работает с JSON без привязки к текущему Event,
+// чтобы старые файлы читались и после изменения структуры.
+var schemaMigrations = []schemaMigration{
+	{
+		from:        0,
+		description: "wrap events into a versioned envelope",
+		apply: func(doc map[string]any) (map[string]any, error) {
+			return map[string]any{"schema_version": 1, "events": doc}, nil
+		},
+	},
+	{
+		from:        1,
+		description: "store start and reminder times in UTC with the event time zone",
+		apply:       normalizeTimes,
+	},
+}
+
+// DecodeCalendar читает документ календаря любой поддерживаемой версии и переводит его в текущую.
+func DecodeCalendar(data []byte) (map[string]*events.Event, *MigrationReport, error) {
+	doc, report, err := migrateDocument(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	var envelope Envelope[*events.Event]
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrCorrupted, err)
+	}
+	if envelope.Events == nil {
+		envelope.Events = make(map[string]*events.Event)
+	}
+	report.Events = len(envelope.Events)
+	return envelope.Events, report, nil
+}
+
+// EncodeCalendar записывает события в документ текущей версии.
+func EncodeCalendar[T any](list map[string]T) ([]byte, error) {
+	if list == nil {
+		list = make(map[string]T)
+	}
+	return json.Marshal(Envelope[T]{SchemaVersion: SchemaVersion, Events: list})
+}
+
+func migrateDocument(data []byte) (map[string]any, *MigrationReport, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrCorrupted, err)
+	}
+	if doc == nil {
+		doc = make(map[string]any)
+	}
+
+	version, err := documentVersion(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	if version > SchemaVersion {
+		return nil, nil, fmt.Errorf("data schema version %d is newer than supported %d", version, SchemaVersion)
+	}
+
+	report := &MigrationReport{From: version, To: SchemaVersion}
+	for _, m := range schemaMigrations[version:] {
+		doc, err = m.apply(doc)
+		if err != nil {
+			return nil, nil, fmt.Errorf("migration from version %d: %w", m.from, err)
+		}
+		report.Steps = append(report.Steps, fmt.Sprintf("%d -> %d: %s", m.from, m.from+1, m.description))
+	}
+	return doc, report, nil
+}
+
+// documentVersion возвращает версию документа; файлы без поля schema_version — это версия 0 (просто карта событий).
+func documentVersion(doc map[string]any) (int, error) {
+	value, ok := doc["schema_version"]
+	if !ok {
+		return 0, nil
+	}
+	version, ok := value.(float64)
+	if !ok || version < 1 || version != float64(int(version)) {
+		return 0, fmt.Errorf("%w: invalid schema_version %v", ErrCorrupted, value)
+	}
+	return int(version), nil
+}
+
+func normalizeTimes(doc map[string]any) (map[string]any, error) {
+	list, _ := doc["events"].(map[string]any)
+	for id, value := range list {
+		e, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("event %s is not an object", id)
+		}
+		// До версии 2 зона не настраивалась: такие события всегда создавались в events.TimeZone,
+		// и текущая зона по умолчанию к ним отношения не имеет.
+		if zone, _ := e["zone"].(string); zone == "" {
+			e["zone"] = events.TimeZone
+		}
+		if err := toUTC(e, "start_at"); err != nil {
+			return nil, fmt.Errorf("event %s: %w", id, err)
+		}
+		if r, ok := e["reminder"].(map[string]any); ok {
+			if err := toUTC(r, "at"); err != nil {
+				return nil, fmt.Errorf("event %s reminder: %w", id, err)
+			}
+		}
+	}
+	doc["schema_version"] = 2
+	return doc, nil
+}
+
+func toUTC(obj map[string]any, key string) error {
+	s, ok := obj[key].(string)
+	if !ok {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", key, err)
+	}
+	obj[key] = t.UTC().Format(time.RFC3339Nano)
+	return nil
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TsSol87/calendarApp/events"
+)
+
+func TestSchemaMigrations_Chain(t *testing.T) {
+	if len(schemaMigrations) != SchemaVersion {
+		t.Fatalf("Expected %d migrations for schema version %d, got %d", SchemaVersion, SchemaVersion, len(schemaMigrations))
+	}
+	for i, m := range schemaMigrations {
+		if m.from != i {
+			t.Errorf("Migration %d starts from version %d", i, m.from)
+		}
+	}
+}
+
+func TestDecodeCalendar_Fixtures(t *testing.T) {
+	meeting := "7f1c7d1e-3a34-4f0e-9d4b-2f1a6c0e5b11"
+	start := time.Date(2025, 3, 10, 7, 0, 0, 0, time.UTC)
+	// Настроенная зона не должна попадать в старые события: они создавались в events.TimeZone.
+	if err := events.SetDefaultZone("Europe/Berlin"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { events.SetDefaultZone(events.TimeZone) })
+	for version := 0; version <= SchemaVersion; version++ {
+		t.Run(fmt.Sprintf("v%d", version), func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", fmt.Sprintf("calendar_v%d.json", version)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			list, report, err := DecodeCalendar(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.From != version || report.To != SchemaVersion || len(report.Steps) != SchemaVersion-version {
+				t.Errorf("Unexpected report: %+v", report)
+			}
+			if report.Events != 2 {
+				t.Errorf("Expected 2 events, got %d", report.Events)
+			}
+
+			e := list[meeting]
+			if e == nil || e.Title != "Team meeting" || e.Priority != "high" {
+				t.Fatalf("Unexpected event: %+v", e)
+			}
+			if !e.StartAt.Equal(start) || e.StartAt.Location() != time.UTC {
+				t.Errorf("Expected start %v in UTC, got %v", start, e.StartAt)
+			}
+			if e.Zone != events.TimeZone {
+				t.Errorf("Expected zone %q, got %q", events.TimeZone, e.Zone)
+			}
+			if e.Reminder == nil || e.Reminder.Message != "Prepare slides" || !e.Reminder.At.Equal(start.Add(-30*time.Minute)) {
+				t.Errorf("Unexpected reminder: %+v", e.Reminder)
+			}
+		})
+	}
+}
+
+func TestDecodeCalendar_NewerVersion(t *testing.T) {
+	data := []byte(fmt.Sprintf(`{"schema_version":%d,"events":{}}`, SchemaVersion+1))
+	if _, _, err := DecodeCalendar(data); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("Expected error for newer schema version, got %v", err)
+	}
+}
+
+func TestBlobRepository_Migrate(t *testing.T) {
+	fixture, err := os.ReadFile(filepath.Join("testdata", "calendar_v0.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewJsonStorage(filepath.Join(t.TempDir(), "data.json"))
+	if err := os.WriteFile(s.GetFilename(), fixture, 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := NewBlobRepository(s)
+
+	report, err := r.Migrate(true)
+	if err != nil || !report.Pending() {
+		t.Fatalf("Expected pending migration, got %+v (%v)", report, err)
+	}
+	if data, _ := os.ReadFile(s.GetFilename()); !bytes.Equal(data, fixture) {
+		t.Errorf("Expected dry run to leave the file untouched")
+	}
+
+	if _, err := r.Migrate(false); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(s.GetFilename())
+	var envelope Envelope[json.RawMessage]
+	if err := json.Unmarshal(data, &envelope); err != nil || envelope.SchemaVersion != SchemaVersion || len(envelope.Events) != 2 {
+		t.Errorf("Expected file upgraded to version %d, got %s", SchemaVersion, data)
+	}
+	if report, _ := r.Migrate(true); report.Pending() {
+		t.Errorf("Expected nothing to migrate after upgrade, got %+v", report)
+	}
+}
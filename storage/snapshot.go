@@ -0,0 +1,354 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TsSol87/calendarApp/events"
+)
+
+const (
+	snapshotLayout = "20060102-150405"
+	snapshotExt    = ".json"
+)
+
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// Snapshot — сохранённая копия календаря; ID — время создания в UTC, а для второго и следующих
+// снимков за ту же секунду — с порядковым номером ("20250310-150405-2").
+type Snapshot struct {
+	ID     string
+	Time   time.Time
+	Events int
+	seq    int
+}
+
+// Retention — сколько последних снимков хранить подряд и сколько последних часов, дней и недель —
+// по одному (самому позднему) снимку.
+type Retention struct {
+	Last   int
+	Hourly int
+	Daily  int
+	Weekly int
+}
+
+// SnapshotStore хранит снимки календаря в отдельном каталоге, по файлу на снимок.
+type SnapshotStore struct {
+	dir  string
+	open func(filename string) Store
+	last []byte
+}
+
+func NewSnapshotStore(dir string) *SnapshotStore {
+	return &SnapshotStore{dir: dir, open: func(filename string) Store { return NewJsonStorage(filename) }}
+}
+
+// WithOpener задаёт хранилище для файлов снимков, например чтобы шифровать их так же, как данные.
+func (s *SnapshotStore) WithOpener(open func(filename string) Store) *SnapshotStore {
+	s.open = open
+	return s
+}
+
+func (s *SnapshotStore) Dir() string {
+	return s.dir
+}
+
+func (s *SnapshotStore) filename(id string) string {
+	return filepath.Join(s.dir, id+snapshotExt)
+}
+
+// Create сохраняет снимок событий; если они не изменились с последнего снимка, новый не создаётся и возвращается false.
+func (s *SnapshotStore) Create(list map[string]*events.Event, at time.Time) (*Snapshot, bool, error) {
+	data, err := EncodeCalendar(list)
+	if err != nil {
+		return nil, false, err
+	}
+	if s.last == nil {
+		if latest, err := s.latest(); err == nil && latest != nil {
+			s.last, _ = s.open(s.filename(latest.ID)).Load()
+		}
+	}
+	if s.last != nil && bytes.Equal(s.last, data) {
+		return nil, false, nil
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, false, err
+	}
+	at = at.UTC()
+	id := s.freeID(at)
+	if err := s.open(s.filename(id)).Save(data); err != nil {
+		return nil, false, fmt.Errorf("can't save snapshot: %w", err)
+	}
+	os.Remove(s.filename(id) + BackupSuffix)
+	s.last = data
+	return &Snapshot{ID: id, Time: at.Truncate(time.Second), Events: len(list)}, true, nil
+}
+
+// freeID возвращает ID снимка, созданного в момент at, не совпадающий с уже сохранёнными.
+func (s *SnapshotStore) freeID(at time.Time) string {
+	base := at.Format(snapshotLayout)
+	id := base
+	for seq := 2; ; seq++ {
+		if _, err := os.Stat(s.filename(id)); err != nil {
+			return id
+		}
+		id = fmt.Sprintf("%s-%d", base, seq)
+	}
+}
+
+// parseSnapshotID разбирает ID снимка на время создания и порядковый номер внутри секунды.
+func parseSnapshotID(id string) (time.Time, int, bool) {
+	if len(id) < len(snapshotLayout) {
+		return time.Time{}, 0, false
+	}
+	at, err := time.Parse(snapshotLayout, id[:len(snapshotLayout)])
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	rest := id[len(snapshotLayout):]
+	if rest == "" {
+		return at, 1, true
+	}
+	suffix, ok := strings.CutPrefix(rest, "-")
+	seq, err := strconv.Atoi(suffix)
+	if !ok || err != nil || seq < 2 {
+		return time.Time{}, 0, false
+	}
+	return at, seq, true
+}
+
+// List возвращает снимки от новых к старым вместе с числом событий в каждом.
+func (s *SnapshotStore) List() ([]Snapshot, error) {
+	list, err := s.scan()
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		found, err := s.Load(list[i].ID)
+		if err != nil {
+			list[i].Events = -1
+			continue
+		}
+		list[i].Events = len(found)
+	}
+	return list, nil
+}
+
+// Find ищет снимок по ID, его началу или номеру в списке (1 — самый новый); точное совпадение ID важнее начала.
+func (s *SnapshotStore) Find(ref string) (*Snapshot, error) {
+	list, err := s.scan()
+	if err != nil {
+		return nil, err
+	}
+	if n, err := strconv.Atoi(ref); err == nil && n >= 1 && n <= len(list) && len(ref) < len(snapshotLayout) {
+		return &list[n-1], nil
+	}
+	var found []Snapshot
+	for _, snapshot := range list {
+		if snapshot.ID == ref {
+			return &snapshot, nil
+		}
+		if strings.HasPrefix(snapshot.ID, ref) {
+			found = append(found, snapshot)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("%w: %q", ErrSnapshotNotFound, ref)
+	case 1:
+		return &found[0], nil
+	default:
+		return nil, fmt.Errorf("snapshot %q is ambiguous: %d snapshots match", ref, len(found))
+	}
+}
+
+func (s *SnapshotStore) Load(id string) (map[string]*events.Event, error) {
+	data, err := s.open(s.filename(id)).Load()
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %q", ErrSnapshotNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	list, _, err := DecodeCalendar(data)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %s: %w", id, err)
+	}
+	return list, nil
+}
+
+// Prune удаляет снимки, которые не нужны по правилам хранения; самый новый снимок остаётся всегда.
+func (s *SnapshotStore) Prune(r Retention) ([]Snapshot, error) {
+	list, err := s.scan()
+	if err != nil {
+		return nil, err
+	}
+	keep := r.Keep(list)
+	var removed []Snapshot
+	var errs []error
+	for _, snapshot := range list {
+		if keep[snapshot.ID] {
+			continue
+		}
+		if err := os.Remove(s.filename(snapshot.ID)); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed = append(removed, snapshot)
+	}
+	return removed, errors.Join(errs...)
+}
+
+// Keep отмечает снимки, которые нужно оставить: r.Last последних и по самому позднему в каждом
+// из последних r.Hourly часов, r.Daily дней и r.Weekly недель, в которых есть снимки.
+// list отсортирован от новых к старым.
+func (r Retention) Keep(list []Snapshot) map[string]bool {
+	keep := make(map[string]bool)
+	for i, snapshot := range list {
+		if i > 0 && i >= r.Last {
+			break
+		}
+		keep[snapshot.ID] = true
+	}
+	periods := []struct {
+		count  int
+		bucket func(t time.Time) string
+	}{
+		{r.Hourly, func(t time.Time) string { return t.Format("2006010215") }},
+		{r.Daily, func(t time.Time) string { return t.Format("20060102") }},
+		{r.Weekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-%02d", year, week)
+		}},
+	}
+	for _, p := range periods {
+		seen := make(map[string]bool)
+		for _, snapshot := range list {
+			if len(seen) >= p.count {
+				break
+			}
+			bucket := p.bucket(snapshot.Time.In(events.ViewerLocation()))
+			if !seen[bucket] {
+				seen[bucket] = true
+				keep[snapshot.ID] = true
+			}
+		}
+	}
+	return keep
+}
+
+func (s *SnapshotStore) latest() (*Snapshot, error) {
+	list, err := s.scan()
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return &list[0], nil
+}
+
+// Latest возвращает время последнего снимка; нулевое время, если снимков нет.
+func (s *SnapshotStore) Latest() (time.Time, error) {
+	latest, err := s.latest()
+	if err != nil || latest == nil {
+		return time.Time{}, err
+	}
+	return latest.Time, nil
+}
+
+func (s *SnapshotStore) scan() ([]Snapshot, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []Snapshot
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), snapshotExt)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		at, seq, ok := parseSnapshotID(id)
+		if !ok {
+			continue
+		}
+		list = append(list, Snapshot{ID: id, Time: at, seq: seq})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].Time.Equal(list[j].Time) {
+			return list[i].Time.After(list[j].Time)
+		}
+		return list[i].seq > list[j].seq
+	})
+	return list, nil
+}
+
+// Encrypt записывает через хранилище снимков (см. WithOpener) снимки, сохранённые без шифрования,
+// например при включении шифрования для календаря, у которого уже есть снимки.
+func (s *SnapshotStore) Encrypt() error {
+	list, err := s.scan()
+	if err != nil {
+		return err
+	}
+	for _, snapshot := range list {
+		filename := s.filename(snapshot.ID)
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("snapshot %s: %w", snapshot.ID, err)
+		}
+		if IsEncrypted(data) {
+			continue
+		}
+		if err := s.open(filename).Save(data); err != nil {
+			return fmt.Errorf("snapshot %s: %w", snapshot.ID, err)
+		}
+		if err := os.Remove(filename + BackupSuffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("snapshot %s: %w", snapshot.ID, err)
+		}
+	}
+	s.last = nil
+	return nil
+}
+
+// Rewrite читает все снимки, вызывает change (например, смену ключа шифрования) и записывает снимки заново.
+// Если записать какой-то снимок не удалось, все файлы снимков возвращаются к прежнему содержимому.
+func (s *SnapshotStore) Rewrite(change func() error) error {
+	list, err := s.scan()
+	if err != nil {
+		return err
+	}
+	data := make(map[string][]byte, len(list))
+	raw := make(map[string][]byte, len(list))
+	for _, snapshot := range list {
+		if data[snapshot.ID], err = s.open(s.filename(snapshot.ID)).Load(); err != nil {
+			return fmt.Errorf("snapshot %s: %w", snapshot.ID, err)
+		}
+		if raw[snapshot.ID], err = os.ReadFile(s.filename(snapshot.ID)); err != nil {
+			return fmt.Errorf("snapshot %s: %w", snapshot.ID, err)
+		}
+	}
+	if err := change(); err != nil {
+		return err
+	}
+	var errs []error
+	for id, d := range data {
+		if err := s.open(s.filename(id)).Save(d); err != nil {
+			errs = append(errs, fmt.Errorf("snapshot %s: %w", id, err))
+		}
+		os.Remove(s.filename(id) + BackupSuffix)
+	}
+	s.last = nil
+	if len(errs) > 0 {
+		for id, d := range raw {
+			errs = append(errs, writeFileAtomic(s.filename(id), d, 0644))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TsSol87/calendarApp/events"
+	"github.com/TsSol87/calendarApp/priority"
+)
+
+func TestSnapshotStore_CreateListFind(t *testing.T) {
+	s := NewSnapshotStore(filepath.Join(t.TempDir(), "snapshots"))
+	at := time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC)
+	list := map[string]*events.Event{
+		"a": {ID: "a", Title: "First", StartAt: at, Priority: priority.PriorityLow},
+	}
+
+	first, created, err := s.Create(list, at)
+	if err != nil || !created {
+		t.Fatalf("Expected snapshot to be created, got %v (%v)", created, err)
+	}
+	if _, created, err := s.Create(list, at.Add(time.Minute)); err != nil || created {
+		t.Errorf("Expected unchanged calendar not to create a snapshot, got %v (%v)", created, err)
+	}
+	list["b"] = &events.Event{ID: "b", Title: "Second", StartAt: at, Priority: priority.PriorityHigh}
+	second, created, err := s.Create(list, at.Add(time.Hour))
+	if err != nil || !created {
+		t.Fatalf("Expected changed calendar to create a snapshot, got %v (%v)", created, err)
+	}
+
+	found, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 || found[0].ID != second.ID || found[0].Events != 2 || found[1].Events != 1 {
+		t.Errorf("Expected newest first with event counts, got %+v", found)
+	}
+	if s, err := s.Find("2"); err != nil || s.ID != first.ID {
+		t.Errorf("Expected number 2 to be the older snapshot, got %v (%v)", s, err)
+	}
+	if s, err := s.Find(second.ID[:11]); err != nil || s.ID != second.ID {
+		t.Errorf("Expected ID prefix to find the snapshot, got %v (%v)", s, err)
+	}
+	if _, err := s.Find("2031"); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Errorf("Expected ErrSnapshotNotFound, got %v", err)
+	}
+	events, err := s.Load(first.ID)
+	if err != nil || len(events) != 1 || events["a"].Title != "First" {
+		t.Errorf("Unexpected snapshot contents %v (%v)", events, err)
+	}
+}
+
+func TestRetention_Keep(t *testing.T) {
+	now := time.Date(2030, 1, 15, 12, 30, 0, 0, time.UTC)
+	var list []Snapshot
+	// Снимки каждые 20 минут за последние 20 дней, от новых к старым.
+	for at := now; at.After(now.AddDate(0, 0, -20)); at = at.Add(-20 * time.Minute) {
+		list = append(list, Snapshot{ID: at.Format(snapshotLayout), Time: at})
+	}
+
+	keep := Retention{Hourly: 3, Daily: 2, Weekly: 2}.Keep(list)
+	// 3 часа (текущий и два предыдущих), вчерашний день и прошлая неделя — остальное совпадает с ними.
+	if len(keep) != 5 {
+		t.Errorf("Expected 5 snapshots to be kept, got %d: %v", len(keep), keep)
+	}
+	if !keep[list[0].ID] {
+		t.Error("Expected the newest snapshot to be kept")
+	}
+
+	if keep := (Retention{}).Keep(list); len(keep) != 1 || !keep[list[0].ID] {
+		t.Errorf("Expected only the newest snapshot without retention, got %v", keep)
+	}
+	if keep := (Retention{Last: 4, Hourly: 1}).Keep(list); len(keep) != 4 || !keep[list[3].ID] {
+		t.Errorf("Expected the last 4 snapshots to be kept, got %v", keep)
+	}
+}
+
+func TestSnapshotStore_CreateWithinOneSecond(t *testing.T) {
+	s := NewSnapshotStore(filepath.Join(t.TempDir(), "snapshots"))
+	at := time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC)
+	list := map[string]*events.Event{
+		"a": {ID: "a", Title: "First", StartAt: at, Priority: priority.PriorityLow},
+	}
+	var created []*Snapshot
+	for i := 0; i < 3; i++ {
+		list["a"].Title = fmt.Sprintf("Title %d", i)
+		snapshot, ok, err := s.Create(list, at.Add(time.Duration(i)*100*time.Millisecond))
+		if err != nil || !ok {
+			t.Fatalf("Expected snapshot %d to be created, got %v (%v)", i, ok, err)
+		}
+		created = append(created, snapshot)
+	}
+	if created[0].ID != "20300101-090000" || created[1].ID != "20300101-090000-2" || created[2].ID != "20300101-090000-3" {
+		t.Fatalf("Expected numbered IDs within one second, got %s %s %s", created[0].ID, created[1].ID, created[2].ID)
+	}
+
+	found, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 3 || found[0].ID != created[2].ID || found[2].ID != created[0].ID {
+		t.Fatalf("Expected all snapshots newest first, got %+v", found)
+	}
+	for i, snapshot := range created {
+		loaded, err := s.Load(snapshot.ID)
+		if err != nil || loaded["a"].Title != fmt.Sprintf("Title %d", i) {
+			t.Errorf("Expected snapshot %s to keep its own contents, got %v (%v)", snapshot.ID, loaded, err)
+		}
+	}
+	if s, err := s.Find(created[0].ID); err != nil || s.ID != created[0].ID {
+		t.Errorf("Expected the exact ID to win over longer IDs with the same start, got %v (%v)", s, err)
+	}
+	if keep := (Retention{Hourly: 1}).Keep(found); !keep[created[2].ID] || keep[created[1].ID] {
+		t.Errorf("Expected the last snapshot of the second to represent the hour, got %v", keep)
+	}
+}
+
+func TestSnapshotStore_Encrypt(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "snapshots")
+	at := time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC)
+	list := map[string]*events.Event{
+		"a": {ID: "a", Title: "Client Acme", StartAt: at, Priority: priority.PriorityLow},
+	}
+	if _, _, err := NewSnapshotStore(dir).Create(list, at); err != nil {
+		t.Fatal(err)
+	}
+
+	data := NewEncryptedStorage(NewJsonStorage(filepath.Join(t.TempDir(), "data.json")), Passphrase([]byte("secret"))).WithScryptParams(testScrypt)
+	s := NewSnapshotStore(dir).WithOpener(func(filename string) Store { return data.Wrap(NewJsonStorage(filename)) })
+	if _, err := s.Load("20300101-090000"); !errors.Is(err, ErrNotEncrypted) {
+		t.Fatalf("Expected a plaintext snapshot before encryption, got %v", err)
+	}
+	if err := s.Encrypt(); err != nil {
+		t.Fatal(err)
+	}
+	if found := plaintextFiles(t, dir, "Client Acme"); len(found) > 0 {
+		t.Fatalf("Expected snapshots to be encrypted, found plaintext in %v", found)
+	}
+	if loaded, err := s.Load("20300101-090000"); err != nil || loaded["a"].Title != "Client Acme" {
+		t.Errorf("Expected the snapshot to open with the data key, got %v (%v)", loaded, err)
+	}
+	// Уже зашифрованные снимки не переписываются.
+	before, _ := os.ReadFile(filepath.Join(dir, "20300101-090000.json"))
+	if err := s.Encrypt(); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := os.ReadFile(filepath.Join(dir, "20300101-090000.json")); !bytes.Equal(before, after) {
+		t.Error("Expected encrypted snapshots to be left as is")
+	}
+}
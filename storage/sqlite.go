@@ -0,0 +1,375 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/TsSol87/calendarApp/events"
+	"github.com/TsSol87/calendarApp/reminder"
+	"github.com/TsSol87/calendarApp/rotate"
+	_ "modernc.org/sqlite"
+)
+
+// migrations применяются по порядку; номер последней применённой хранится в PRAGMA user_version.
+var migrations = []string{
+	`CREATE TABLE events (
+		id       TEXT PRIMARY KEY,
+		title    TEXT NOT NULL,
+		start_at INTEGER NOT NULL,
+		zone     TEXT NOT NULL DEFAULT '',
+		priority TEXT NOT NULL,
+		data     TEXT NOT NULL
+	);
+	CREATE INDEX events_start_at ON events(start_at);
+	CREATE INDEX events_priority ON events(priority);
+	CREATE TABLE reminders (
+		event_id TEXT PRIMARY KEY REFERENCES events(id) ON DELETE CASCADE,
+		message  TEXT NOT NULL,
+		at       INTEGER NOT NULL,
+		sent     INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX reminders_at ON reminders(at);
+	CREATE TABLE history (
+		id        INTEGER PRIMARY KEY AUTOINCREMENT,
+		message   TEXT NOT NULL,
+		timestamp INTEGER NOT NULL
+	);`,
+	`ALTER TABLE history ADD COLUMN kind TEXT NOT NULL DEFAULT '';`,
+}
+
+type SQLiteStorage struct {
+	*Storage
+	db *sql.DB
+}
+
+func NewSQLiteStorage(filename string) (*SQLiteStorage, error) {
+	dsn := "file:" + filename + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("can't open database %s: %w", filename, err)
+	}
+	s := &SQLiteStorage{Storage: &Storage{filename: filename}, db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("can't migrate database %s: %w", filename, err)
+	}
+	return s, nil
+}
+
+func (s *SQLiteStorage) Close() error {
+	return s.db.Close()
+}
+
+// SchemaVersion возвращает номер последней применённой миграции.
+func (s *SQLiteStorage) SchemaVersion() (int, error) {
+	var version int
+	err := s.db.QueryRow("PRAGMA user_version").Scan(&version)
+	return version, err
+}
+
+func (s *SQLiteStorage) migrate() error {
+	version, err := s.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("database schema version %d is newer than supported %d", version, len(migrations))
+	}
+	for i := version; i < len(migrations); i++ {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save заменяет содержимое базы целым календарём в формате JsonStorage.
+// Нужен для совместимости со Store; календарь пишет события по одному через Repository.
+func (s *SQLiteStorage) Save(data []byte) error {
+	list, _, err := DecodeCalendar(data)
+	if err != nil {
+		return err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM events"); err != nil {
+		tx.Rollback()
+		return err
+	}
+	repo := &sqliteRepo{q: tx}
+	for _, e := range list {
+		if err := repo.Put(e); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStorage) Load() ([]byte, error) {
+	list, err := s.All()
+	if err != nil {
+		return nil, err
+	}
+	return EncodeCalendar(list)
+}
+
+// Migrate сообщает версию схемы базы: её миграции применяются при открытии.
+func (s *SQLiteStorage) Migrate(dryRun bool) (*MigrationReport, error) {
+	version, err := s.SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+	var count int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM events").Scan(&count); err != nil {
+		return nil, err
+	}
+	return &MigrationReport{From: version, To: len(migrations), Events: count}, nil
+}
+
+func (s *SQLiteStorage) repo() *sqliteRepo {
+	return &sqliteRepo{q: s.db}
+}
+
+func (s *SQLiteStorage) Get(id string) (*events.Event, error) {
+	return s.repo().Get(id)
+}
+
+func (s *SQLiteStorage) Put(e *events.Event) error {
+	return s.Transaction(func(tx Repository) error { return tx.Put(e) })
+}
+
+func (s *SQLiteStorage) Delete(id string) error {
+	return s.repo().Delete(id)
+}
+
+func (s *SQLiteStorage) Range(from, to time.Time) ([]*events.Event, error) {
+	return s.repo().Range(from, to)
+}
+
+func (s *SQLiteStorage) All() (map[string]*events.Event, error) {
+	return s.repo().All()
+}
+
+func (s *SQLiteStorage) Transaction(fn func(tx Repository) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(&sqliteRepo{q: tx}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// queryer — общее у *sql.DB и *sql.Tx, чтобы одни и те же запросы работали внутри транзакции и вне её.
+type queryer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+type sqliteRepo struct {
+	q queryer
+}
+
+const selectEvents = `SELECT e.data, r.message, r.at, r.sent
+	FROM events e LEFT JOIN reminders r ON r.event_id = e.id`
+
+func (r *sqliteRepo) Get(id string) (*events.Event, error) {
+	list, err := r.query(selectEvents+" WHERE e.id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrNotFound, id)
+	}
+	return list[0], nil
+}
+
+func (r *sqliteRepo) Range(from, to time.Time) ([]*events.Event, error) {
+	return r.query(selectEvents+" WHERE e.start_at >= ? AND e.start_at < ? ORDER BY e.start_at", from.Unix(), to.Unix())
+}
+
+func (r *sqliteRepo) All() (map[string]*events.Event, error) {
+	list, err := r.query(selectEvents + " ORDER BY e.start_at")
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]*events.Event, len(list))
+	for _, e := range list {
+		result[e.ID] = e
+	}
+	return result, nil
+}
+
+func (r *sqliteRepo) Transaction(fn func(tx Repository) error) error {
+	return fn(r)
+}
+
+func (r *sqliteRepo) query(query string, args ...any) ([]*events.Event, error) {
+	rows, err := r.q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]*events.Event, 0)
+	for rows.Next() {
+		var data string
+		var message sql.NullString
+		var at sql.NullInt64
+		var sent sql.NullBool
+		if err := rows.Scan(&data, &message, &at, &sent); err != nil {
+			return nil, err
+		}
+		var e events.Event
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			return nil, fmt.Errorf("%w: event data: %v", ErrCorrupted, err)
+		}
+		if message.Valid {
+			e.Reminder = &reminder.Reminder{Message: message.String, At: time.Unix(at.Int64, 0).UTC(), Sent: sent.Bool}
+		}
+		list = append(list, &e)
+	}
+	return list, rows.Err()
+}
+
+func (r *sqliteRepo) Delete(id string) error {
+	res, err := r.q.Exec("DELETE FROM events WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%w: %q", ErrNotFound, id)
+	}
+	return nil
+}
+
+func (r *sqliteRepo) Put(e *events.Event) error {
+	row := *e
+	row.Reminder = nil
+	data, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	_, err = r.q.Exec(`INSERT INTO events (id, title, start_at, zone, priority, data) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET title = excluded.title, start_at = excluded.start_at,
+			zone = excluded.zone, priority = excluded.priority, data = excluded.data`,
+		e.ID, e.Title, e.StartAt.Unix(), e.Zone, string(e.Priority), string(data))
+	if err != nil {
+		return fmt.Errorf("can't save event %s: %w", e.ID, err)
+	}
+
+	if e.Reminder == nil {
+		_, err = r.q.Exec("DELETE FROM reminders WHERE event_id = ?", e.ID)
+		return err
+	}
+	_, err = r.q.Exec(`INSERT INTO reminders (event_id, message, at, sent) VALUES (?, ?, ?, ?)
+		ON CONFLICT(event_id) DO UPDATE SET message = excluded.message, at = excluded.at, sent = excluded.sent`,
+		e.ID, e.Reminder.Message, e.Reminder.At.Unix(), e.Reminder.Sent)
+	if err != nil {
+		return fmt.Errorf("can't save reminder for event %s: %w", e.ID, err)
+	}
+	return nil
+}
+
+// History возвращает историю команд в той же базе: каждая запись — строка таблицы history.
+// Старые строки удаляются по тем же правилам, по которым HistoryFile удаляет архивные части:
+// старше opt.MaxAge и сверх объёма opt.MaxFiles частей по opt.MaxSize байт вместе с текущей.
+func (s *SQLiteStorage) History(opt rotate.Options) History {
+	return &sqliteHistory{s, opt}
+}
+
+// historyRow — запись истории; Seq — номер строки в таблице, запись хранит его в поле id.
+type historyRow struct {
+	Seq       int64  `json:",omitempty"`
+	Kind      string `json:",omitempty"`
+	Message   string
+	Timestamp time.Time
+}
+
+type sqliteHistory struct {
+	*SQLiteStorage
+	opt rotate.Options
+}
+
+// historyRowOverhead — примерно столько байт занимает запись в файле истории помимо вида и текста.
+const historyRowOverhead = 80
+
+// Append добавляет строку; номер ей назначает база, Seq записи не используется.
+func (h *sqliteHistory) Append(record []byte) error {
+	var r historyRow
+	if err := json.Unmarshal(record, &r); err != nil {
+		return fmt.Errorf("history record is not valid JSON: %w", err)
+	}
+	_, err := h.db.Exec("INSERT INTO history (kind, message, timestamp) VALUES (?, ?, ?)", r.Kind, r.Message, r.Timestamp.UnixNano())
+	if err != nil {
+		return err
+	}
+	return h.trim(time.Now())
+}
+
+// trim удаляет строки истории, которые HistoryFile уже не хранил бы.
+func (h *sqliteHistory) trim(now time.Time) error {
+	if h.opt.MaxAge > 0 {
+		if _, err := h.db.Exec("DELETE FROM history WHERE timestamp < ?", now.Add(-h.opt.MaxAge).UnixNano()); err != nil {
+			return fmt.Errorf("can't remove old history: %w", err)
+		}
+	}
+	if h.opt.MaxSize > 0 && h.opt.MaxFiles > 0 {
+		_, err := h.db.Exec(`DELETE FROM history WHERE id <= (
+			SELECT id FROM (
+				SELECT id, SUM(length(CAST(kind AS BLOB)) + length(CAST(message AS BLOB)) + ?) OVER (ORDER BY id DESC) AS total
+				FROM history
+			) WHERE total > ? ORDER BY id DESC LIMIT 1)`,
+			historyRowOverhead, h.opt.MaxSize*int64(h.opt.MaxFiles+1))
+		if err != nil {
+			return fmt.Errorf("can't remove old history: %w", err)
+		}
+	}
+	return nil
+}
+
+func (h *sqliteHistory) Scan(fn func(record []byte) error) error {
+	rows, err := h.db.Query("SELECT id, kind, message, timestamp FROM history ORDER BY id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r historyRow
+		var ts int64
+		if err := rows.Scan(&r.Seq, &r.Kind, &r.Message, &ts); err != nil {
+			return err
+		}
+		r.Timestamp = time.Unix(0, ts)
+		record, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+var _ Repository = (*SQLiteStorage)(nil)
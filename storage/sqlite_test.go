@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TsSol87/calendarApp/events"
+	"github.com/TsSol87/calendarApp/priority"
+	"github.com/TsSol87/calendarApp/reminder"
+	"github.com/TsSol87/calendarApp/rotate"
+)
+
+func openSQLite(t *testing.T, filename string) *SQLiteStorage {
+	t.Helper()
+	s, err := NewSQLiteStorage(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSQLiteStorage_PutAndDelete(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data.db")
+	s := openSQLite(t, filename)
+
+	at := time.Date(2030, 1, 2, 10, 0, 0, 0, time.UTC)
+	first := &events.Event{ID: "1", Title: "First", StartAt: at, Priority: priority.PriorityHigh,
+		Reminder: &reminder.Reminder{Message: "soon", At: at.Add(-time.Hour)}}
+	second := &events.Event{ID: "2", Title: "Second", StartAt: at.Add(time.Hour), Priority: priority.PriorityLow}
+	for _, e := range []*events.Event{first, second} {
+		if err := s.Put(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first.Title = "Renamed"
+	first.Reminder = nil
+	if err := s.Put(first); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("2"); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := openSQLite(t, filename).All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list["1"].Title != "Renamed" || list["1"].Reminder != nil {
+		t.Errorf("Unexpected events after upsert and delete: %+v", list)
+	}
+	if !list["1"].StartAt.Equal(at) {
+		t.Errorf("Expected start %v, got %v", at, list["1"].StartAt)
+	}
+}
+
+func TestSQLiteStorage_SaveLoadAndReminder(t *testing.T) {
+	s := openSQLite(t, filepath.Join(t.TempDir(), "data.db"))
+	at := time.Date(2030, 1, 2, 10, 0, 0, 0, time.UTC)
+	data, _ := json.Marshal(map[string]*events.Event{
+		"1": {ID: "1", Title: "Event", StartAt: at, Priority: priority.PriorityMedium,
+			Reminder: &reminder.Reminder{Message: "ping", At: at, Sent: true}},
+	})
+	if err := s.Save(data); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, _, err := DecodeCalendar(loaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := list["1"].Reminder
+	if r == nil || r.Message != "ping" || !r.Sent || !r.At.Equal(at) {
+		t.Errorf("Expected reminder to survive round trip, got %+v", r)
+	}
+}
+
+func TestSQLiteStorage_HistoryAppendsAndMigrations(t *testing.T) {
+	s := openSQLite(t, filepath.Join(t.TempDir(), "data.db"))
+	if v, err := s.SchemaVersion(); err != nil || v != len(migrations) {
+		t.Errorf("Expected schema version %d, got %d (%v)", len(migrations), v, err)
+	}
+
+	history := s.History(rotate.Options{})
+	now := time.Now()
+	for _, msg := range []string{"add", "list"} {
+		data, _ := json.Marshal(historyRow{Kind: "command", Message: msg, Timestamp: now})
+		if err := history.Append(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []historyRow
+	err := history.Scan(func(record []byte) error {
+		var r historyRow
+		if err := json.Unmarshal(record, &r); err != nil {
+			return err
+		}
+		got = append(got, r)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Message != "add" || got[1].Message != "list" || got[1].Kind != "command" || !got[0].Timestamp.Equal(now) {
+		t.Errorf("Expected history to be appended in order, got %+v", got)
+	}
+	if len(got) == 2 && (got[0].Seq != 1 || got[1].Seq != 2) {
+		t.Errorf("Expected rows to be numbered by id, got %d and %d", got[0].Seq, got[1].Seq)
+	}
+}
+
+func TestSQLiteStorage_HistoryRetention(t *testing.T) {
+	s := openSQLite(t, filepath.Join(t.TempDir(), "data.db"))
+	messages := func(h History) []string {
+		var list []string
+		h.Scan(func(record []byte) error {
+			var r historyRow
+			json.Unmarshal(record, &r)
+			list = append(list, r.Message)
+			return nil
+		})
+		return list
+	}
+
+	// Две части по 300 байт: строка с текстом в 50 байт занимает около 137, так что остаются четыре последние.
+	bySize := s.History(rotate.Options{MaxSize: 300, MaxFiles: 1})
+	for i := range 10 {
+		data, _ := json.Marshal(historyRow{Kind: "command", Message: fmt.Sprintf("%02d%s", i, strings.Repeat("x", 48)), Timestamp: time.Now()})
+		if err := bySize.Append(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := messages(bySize)
+	if len(got) != 4 || !strings.HasPrefix(got[0], "06") || !strings.HasPrefix(got[3], "09") {
+		t.Errorf("Expected the last four rows to be kept, got %v", got)
+	}
+
+	byAge := s.History(rotate.Options{MaxAge: time.Hour})
+	old, _ := json.Marshal(historyRow{Message: "old", Timestamp: time.Now().Add(-2 * time.Hour)})
+	fresh, _ := json.Marshal(historyRow{Message: "fresh", Timestamp: time.Now()})
+	for _, data := range [][]byte{old, fresh} {
+		if err := byAge.Append(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := messages(byAge); slices.Contains(got, "old") || !slices.Contains(got, "fresh") {
+		t.Errorf("Expected rows older than MaxAge to be removed, got %v", got)
+	}
+}
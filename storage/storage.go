@@ -1,5 +1,13 @@
 package storage
 
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/TsSol87/calendarApp/rotate"
+)
+
 type Storage struct {
 	filename string
 }
@@ -7,3 +15,46 @@ type Storage struct {
 func (s *Storage) GetFilename() string {
 	return s.filename
 }
+
+type Options struct {
+	Compression  Compression
+	CompactEvery int
+	History      rotate.Options
+}
+
+// Open создаёт хранилища событий и истории команд для выбранного бэкенда.
+// История дописывается в historyFile и ротируется по opt.History; в SQLite она хранится отдельной таблицей,
+// из которой старые строки удаляются по тем же правилам.
+// Историю, которую zip-архив хранил отдельной записью, historyFile забирает при первом открытии.
+func Open(kind string, dataFile string, historyFile string, opt Options) (Store, History, error) {
+	history := NewHistoryFile(historyFile, opt.History)
+	switch kind {
+	case "json":
+		return NewJsonStorage(dataFile), history, nil
+	case "zip":
+		if err := ValidateCompression(opt.Compression); err != nil {
+			return nil, nil, err
+		}
+		if filepath.Ext(dataFile) == ".json" {
+			dataFile = strings.TrimSuffix(dataFile, ".json") + ".zip"
+		}
+		z := NewZipStorage(dataFile).WithCompression(opt.Compression)
+		return z, history.WithLegacy(z.Entry(HistoryEntry)), nil
+	case "sqlite":
+		if filepath.Ext(dataFile) == ".json" {
+			dataFile = strings.TrimSuffix(dataFile, ".json") + ".db"
+		}
+		s, err := NewSQLiteStorage(dataFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		return s, s.History(opt.History), nil
+	case "journal":
+		return NewJournalStorage(dataFile, opt.CompactEvery), history, nil
+	case "git":
+		// Репозиторий — каталог рядом с файлом данных: calendar_data.json -> calendar_data.git/.
+		return NewGitStorage(strings.TrimSuffix(dataFile, ".json") + ".git"), history, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown storage backend %q", kind)
+	}
+}
@@ -5,3 +5,8 @@ type Store interface {
 	Load() ([]byte, error)
 	GetFilename() string
 }
+
+type Recoverable interface {
+	BackupFilename() string
+	Recover() error
+}
@@ -2,51 +2,387 @@ package storage
 
 import (
 	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+type Compression string
+
+const (
+	CompressionDeflate Compression = "deflate"
+	CompressionZstd    Compression = "zstd"
+	CompressionGzip    Compression = "gzip"
+)
+
+var Compressions = []Compression{CompressionDeflate, CompressionZstd, CompressionGzip}
+
+const (
+	ZipSchemaVersion = 1
+	ManifestEntry    = "manifest.json"
+	EventsEntry      = "events.json"
+	HistoryEntry     = "history.json"
+	zstdMethod       = 93
+	gzipSuffix       = ".gz"
 )
 
+type ManifestItem struct {
+	Name   string `json:"name"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+type Manifest struct {
+	SchemaVersion int                     `json:"schema_version"`
+	Compression   Compression             `json:"compression"`
+	UpdatedAt     time.Time               `json:"updated_at"`
+	Entries       map[string]ManifestItem `json:"entries"`
+}
+
 type ZipStorage struct {
 	*Storage
+	entry       string
+	compression Compression
 }
 
+var archiveLocks sync.Map
+
+// archiveLockSuffix отличается от LockSuffix, чтобы не пересекаться с блокировкой BlobRepository на том же архиве.
+const archiveLockSuffix = ".archive"
+
 func NewZipStorage(filename string) *ZipStorage {
 	return &ZipStorage{
-		&Storage{filename: filename},
+		Storage:     &Storage{filename: filename},
+		entry:       EventsEntry,
+		compression: CompressionDeflate,
 	}
 }
 
+func ValidateCompression(c Compression) error {
+	for _, known := range Compressions {
+		if c == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown compression %q", c)
+}
+
+// WithCompression задаёт сжатие, которым архив будет записан при следующем сохранении.
+func (z *ZipStorage) WithCompression(c Compression) *ZipStorage {
+	clone := *z
+	clone.compression = c
+	return &clone
+}
+
+// Entry возвращает хранилище для другой записи того же архива, например истории команд.
+func (z *ZipStorage) Entry(name string) *ZipStorage {
+	clone := *z
+	clone.entry = name
+	return &clone
+}
+
+// lock защищает чтение и перезапись архива: записи событий и истории делят один файл,
+// поэтому блокировка берётся и внутри процесса, и между процессами. Без блокировки между процессами
+// архив не читается и не пишется, иначе запись другого процесса была бы молча потеряна.
+func (z *ZipStorage) lock() (func(), error) {
+	m, _ := archiveLocks.LoadOrStore(z.GetFilename(), &sync.Mutex{})
+	mutex := m.(*sync.Mutex)
+	mutex.Lock()
+	l, err := LockFile(z.GetFilename()+archiveLockSuffix, true)
+	if err != nil {
+		mutex.Unlock()
+		if errors.Is(err, ErrLocked) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %w", ErrLocked, err)
+	}
+	return func() {
+		l.Unlock()
+		mutex.Unlock()
+	}, nil
+}
+
 func (z *ZipStorage) Save(data []byte) error {
-	f, err := os.Create(z.GetFilename())
-	defer f.Close()
+	if err := ValidateCompression(z.compression); err != nil {
+		return err
+	}
+	unlock, err := z.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
-	zw := zip.NewWriter(f)
-	defer zw.Close()
+	entries, legacy, err := z.readAll()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("can't read existing archive %s: %w", z.GetFilename(), err)
+	}
+	if entries == nil {
+		entries = make(map[string][]byte)
+	}
+	// События из архива старого формата переносятся в свою запись, даже если сохраняется другая.
+	if legacy != nil {
+		entries[EventsEntry] = legacy
+	}
+	entries[z.entry] = data
 
-	w, err := zw.Create("data")
-	_, err = w.Write(data)
-	return err
+	archive, err := z.build(entries)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(z.GetFilename(), archive, 0644)
 }
 
 func (z *ZipStorage) Load() ([]byte, error) {
+	unlock, err := z.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	entries, legacy, err := z.readAll()
+	if err != nil {
+		return nil, err
+	}
+	if data, ok := entries[z.entry]; ok {
+		return data, nil
+	}
+	if legacy != nil && z.entry == EventsEntry {
+		return legacy, nil
+	}
+	return nil, fmt.Errorf("entry %q not found in %s: %w", z.entry, z.GetFilename(), os.ErrNotExist)
+}
+
+func (z *ZipStorage) BackupFilename() string {
+	return z.GetFilename() + BackupSuffix
+}
+
+// Recover заменяет архив резервной копией, если она читается и проходит проверку контрольных сумм.
+func (z *ZipStorage) Recover() error {
+	backup := &ZipStorage{Storage: &Storage{filename: z.BackupFilename()}, entry: z.entry, compression: z.compression}
+	if _, _, err := backup.readAll(); err != nil {
+		return fmt.Errorf("backup %s is not usable: %w", z.BackupFilename(), err)
+	}
+	data, err := os.ReadFile(z.BackupFilename())
+	if err != nil {
+		return err
+	}
+	unlock, err := z.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	corrupted := fmt.Sprintf("%s.corrupt-%s", z.GetFilename(), time.Now().Format("20060102-150405"))
+	if err := os.Rename(z.GetFilename(), corrupted); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return writeFileAtomic(z.GetFilename(), data, 0644)
+}
+
+func (z *ZipStorage) Manifest() (*Manifest, error) {
+	unlock, err := z.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 	r, err := zip.OpenReader(z.GetFilename())
 	if err != nil {
 		return nil, err
 	}
 	defer r.Close()
+	return readManifest(&r.Reader)
+}
+
+func (z *ZipStorage) build(entries map[string][]byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	zw.RegisterCompressor(zstdMethod, func(w io.Writer) (io.WriteCloser, error) {
+		return zstd.NewWriter(w)
+	})
 
-	if len(r.File) == 0 {
-		return nil, errors.New("архив пуст")
+	manifest := Manifest{
+		SchemaVersion: ZipSchemaVersion,
+		Compression:   z.compression,
+		UpdatedAt:     time.Now().UTC(),
+		Entries:       make(map[string]ManifestItem),
 	}
 
-	file := r.File[0]
-	rc, err := file.Open()
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		data := entries[name]
+		sum := sha256.Sum256(data)
+		file, err := z.writeEntry(zw, name, data)
+		if err != nil {
+			zw.Close()
+			return nil, fmt.Errorf("can't write entry %q: %w", name, err)
+		}
+		manifest.Entries[name] = ManifestItem{Name: file, Size: len(data), SHA256: hex.EncodeToString(sum[:])}
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
 	if err != nil {
+		zw.Close()
 		return nil, err
 	}
-	defer rc.Close()
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: ManifestEntry, Method: zip.Deflate, Modified: manifest.UpdatedAt})
+	if err != nil {
+		zw.Close()
+		return nil, err
+	}
+	if _, err := w.Write(manifestData); err != nil {
+		zw.Close()
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("can't finish archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (z *ZipStorage) writeEntry(zw *zip.Writer, name string, data []byte) (string, error) {
+	header := &zip.FileHeader{Name: name, Modified: time.Now().UTC()}
+	switch z.compression {
+	case CompressionZstd:
+		header.Method = zstdMethod
+	case CompressionGzip:
+		header.Name += gzipSuffix
+		header.Method = zip.Store
+		var gz bytes.Buffer
+		gw := gzip.NewWriter(&gz)
+		if _, err := gw.Write(data); err != nil {
+			return "", err
+		}
+		if err := gw.Close(); err != nil {
+			return "", err
+		}
+		data = gz.Bytes()
+	default:
+		header.Method = zip.Deflate
+	}
+
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(data); err != nil {
+		return "", err
+	}
+	return header.Name, nil
+}
 
+// readAll читает все записи архива и сверяет их с контрольными суммами манифеста.
+// Для архивов старого формата с единственной записью "data" она возвращается отдельно.
+func (z *ZipStorage) readAll() (map[string][]byte, []byte, error) {
+	r, err := zip.OpenReader(z.GetFilename())
+	if errors.Is(err, zip.ErrFormat) {
+		return nil, nil, fmt.Errorf("%w: %s: %v", ErrCorrupted, z.GetFilename(), err)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	defer r.Close()
+	r.RegisterDecompressor(zstdMethod, func(rd io.Reader) io.ReadCloser {
+		d, err := zstd.NewReader(rd)
+		if err != nil {
+			return io.NopCloser(errReader{err})
+		}
+		return d.IOReadCloser()
+	})
+
+	manifest, err := readManifest(&r.Reader)
+	if errors.Is(err, os.ErrNotExist) {
+		if len(r.File) == 0 {
+			return nil, nil, fmt.Errorf("%w: archive %s is empty", ErrCorrupted, z.GetFilename())
+		}
+		legacy, err := readZipFile(r.File[0])
+		return nil, legacy, err
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if manifest.SchemaVersion > ZipSchemaVersion {
+		return nil, nil, fmt.Errorf("archive schema version %d is newer than supported %d", manifest.SchemaVersion, ZipSchemaVersion)
+	}
+
+	files := make(map[string]*zip.File)
+	for _, f := range r.File {
+		files[f.Name] = f
+	}
+	entries := make(map[string][]byte)
+	for name, item := range manifest.Entries {
+		f, ok := files[item.Name]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: entry %q listed in manifest is missing", ErrCorrupted, item.Name)
+		}
+		data, err := readZipFile(f)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: can't read entry %q: %v", ErrCorrupted, item.Name, err)
+		}
+		if item.Name == name+gzipSuffix {
+			gr, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w: entry %q: %v", ErrCorrupted, item.Name, err)
+			}
+			data, err = io.ReadAll(gr)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w: entry %q: %v", ErrCorrupted, item.Name, err)
+			}
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != item.SHA256 {
+			return nil, nil, fmt.Errorf("%w: checksum mismatch for entry %q", ErrCorrupted, name)
+		}
+		entries[name] = data
+	}
+	return entries, nil, nil
+}
+
+func readManifest(r *zip.Reader) (*Manifest, error) {
+	for _, f := range r.File {
+		if f.Name != ManifestEntry {
+			continue
+		}
+		data, err := readZipFile(f)
+		if err != nil {
+			return nil, err
+		}
+		var m Manifest
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("%w: invalid manifest: %v", ErrCorrupted, err)
+		}
+		return &m, nil
+	}
+	return nil, os.ErrNotExist
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
 	return io.ReadAll(rc)
+}
+
+type errReader struct {
+	err error
+}
 
+func (e errReader) Read([]byte) (int, error) {
+	return 0, e.err
 }
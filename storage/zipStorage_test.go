@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestZipStorage_CompressionsAndEntries(t *testing.T) {
+	for _, c := range Compressions {
+		t.Run(string(c), func(t *testing.T) {
+			z := NewZipStorage(filepath.Join(t.TempDir(), "data.zip")).WithCompression(c)
+			history := z.Entry(HistoryEntry)
+			if err := z.Save([]byte(`{"events":true}`)); err != nil {
+				t.Fatal(err)
+			}
+			if err := history.Save([]byte(`["history"]`)); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := z.Load()
+			if err != nil || string(data) != `{"events":true}` {
+				t.Errorf("Expected events entry, got %q (%v)", data, err)
+			}
+			data, err = history.Load()
+			if err != nil || string(data) != `["history"]` {
+				t.Errorf("Expected history entry, got %q (%v)", data, err)
+			}
+
+			m, err := z.Manifest()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.SchemaVersion != ZipSchemaVersion || m.Compression != c || len(m.Entries) != 2 {
+				t.Errorf("Unexpected manifest: %+v", m)
+			}
+		})
+	}
+}
+
+func TestZipStorage_ChecksumMismatch(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data.zip")
+	z := NewZipStorage(filename)
+	if err := z.Save([]byte(`{"v":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	m, err := z.Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create(EventsEntry)
+	w.Write([]byte(`{"v":2}`))
+	w, _ = zw.Create(ManifestEntry)
+	w.Write([]byte(`{"schema_version":1,"entries":{"events.json":{"name":"events.json","sha256":"` + m.Entries[EventsEntry].SHA256 + `"}}}`))
+	zw.Close()
+	if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := z.Load(); !errors.Is(err, ErrCorrupted) {
+		t.Errorf("Expected ErrCorrupted for tampered entry, got %v", err)
+	}
+}
+
+func TestZipStorage_LegacyArchive(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data.zip")
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("data")
+	w.Write([]byte(`{"legacy":true}`))
+	zw.Close()
+	if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	z := NewZipStorage(filename)
+	data, err := z.Load()
+	if err != nil || string(data) != `{"legacy":true}` {
+		t.Fatalf("Expected legacy data, got %q (%v)", data, err)
+	}
+
+	// Первой в архив старого формата может записаться история команд.
+	if err := z.Entry(HistoryEntry).Save([]byte(`[]`)); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := z.Load(); err != nil || string(data) != `{"legacy":true}` {
+		t.Fatalf("Expected legacy events to survive saving another entry, got %q (%v)", data, err)
+	}
+	if err := z.Save(data); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := z.Manifest(); err != nil {
+		t.Errorf("Expected manifest after re-saving legacy archive, got %v", err)
+	}
+}
+
+func TestZipStorage_Truncated(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data.zip")
+	if err := os.WriteFile(filename, []byte("PK\x03\x04broken"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewZipStorage(filename).Load(); !errors.Is(err, ErrCorrupted) {
+		t.Errorf("Expected ErrCorrupted for truncated archive, got %v", err)
+	}
+}
+
+func TestZipStorage_RefusesToWriteWithoutLock(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data.zip")
+	z := NewZipStorage(filename)
+	if err := z.Save([]byte(`{"a":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	// Каталог на месте файла блокировки не даёт её взять.
+	os.Remove(filename + archiveLockSuffix + LockSuffix)
+	if err := os.Mkdir(filename+archiveLockSuffix+LockSuffix, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.Save([]byte(`{"a":2}`)); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked when the archive can't be locked, got %v", err)
+	}
+	if _, err := z.Load(); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked on load, got %v", err)
+	}
+	os.Remove(filename + archiveLockSuffix + LockSuffix)
+	if data, err := z.Load(); err != nil || string(data) != `{"a":1}` {
+		t.Errorf("Expected the archive to stay unchanged, got %q (%v)", data, err)
+	}
+}
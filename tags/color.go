@@ -0,0 +1,110 @@
+package tags
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// Цвета терминала, которые можно назначить метке.
+var colorCodes = map[string]string{
+	"red":     "31",
+	"green":   "32",
+	"yellow":  "33",
+	"blue":    "34",
+	"magenta": "35",
+	"cyan":    "36",
+	"gray":    "90",
+}
+
+// palette — цвета для меток без назначенного цвета; метка получает цвет по хешу имени,
+// поэтому он не меняется между запусками.
+var palette = []string{"green", "yellow", "blue", "magenta", "cyan", "red"}
+
+var (
+	colorMutex sync.RWMutex
+	colors     = map[string]string{}
+	enabled    = os.Getenv("NO_COLOR") == "" && term.IsTerminal(int(os.Stdout.Fd()))
+)
+
+// ColorNames возвращает названия доступных цветов.
+func ColorNames() []string {
+	names := make([]string, 0, len(colorCodes))
+	for name := range colorCodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseColors разбирает назначения цветов вида "work=blue,personal=green".
+func ParseColors(s string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, item := range strings.Split(s, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		tag, color, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("tag color %q must look like tag=color", item)
+		}
+		tag, err := Normalize(tag)
+		if err != nil {
+			return nil, err
+		}
+		color = strings.ToLower(strings.TrimSpace(color))
+		if _, ok := colorCodes[color]; !ok {
+			return nil, fmt.Errorf("unknown color %q for tag %q (use %s)", color, tag, strings.Join(ColorNames(), ", "))
+		}
+		result[tag] = color
+	}
+	return result, nil
+}
+
+// SetColors назначает меткам цвета вида "work=blue,personal=green".
+func SetColors(s string) error {
+	parsed, err := ParseColors(s)
+	if err != nil {
+		return err
+	}
+	colorMutex.Lock()
+	colors = parsed
+	colorMutex.Unlock()
+	return nil
+}
+
+// SetEnabled включает или выключает цвета; по умолчанию они включены, если вывод идёт в терминал и не задан NO_COLOR.
+func SetEnabled(on bool) {
+	colorMutex.Lock()
+	enabled = on
+	colorMutex.Unlock()
+}
+
+// Color возвращает цвет метки: назначенный в настройках или выбранный из палитры.
+func Color(tag string) string {
+	colorMutex.RLock()
+	color, ok := colors[tag]
+	colorMutex.RUnlock()
+	if ok {
+		return color
+	}
+	h := fnv.New32a()
+	h.Write([]byte(tag))
+	return palette[h.Sum32()%uint32(len(palette))]
+}
+
+// Paint возвращает метку с # в её цвете, если цвета включены.
+func Paint(tag string) string {
+	colorMutex.RLock()
+	on := enabled
+	colorMutex.RUnlock()
+	if !on {
+		return "#" + tag
+	}
+	return "\x1b[" + colorCodes[Color(tag)] + "m#" + tag + "\x1b[0m"
+}
@@ -0,0 +1,51 @@
+package tags
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var ErrIsValidTag = errors.New("tag: invalid tag")
+
+var tagPattern = regexp.MustCompile(`^[\p{L}0-9_-]{1,30}$`)
+
+// Normalize приводит метку к виду, в котором она хранится: без ведущего # и в нижнем регистре.
+func Normalize(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+	if !tagPattern.MatchString(tag) {
+		return "", fmt.Errorf("%w %q: use up to 30 letters, digits, '-' or '_'", ErrIsValidTag, tag)
+	}
+	return tag, nil
+}
+
+// Parse разбирает метки, перечисленные через запятую или отдельными аргументами, без повторов.
+func Parse(args ...string) ([]string, error) {
+	var list []string
+	for _, arg := range args {
+		for _, item := range strings.Split(arg, ",") {
+			if strings.TrimSpace(item) == "" {
+				continue
+			}
+			tag, err := Normalize(item)
+			if err != nil {
+				return nil, err
+			}
+			if !slices.Contains(list, tag) {
+				list = append(list, tag)
+			}
+		}
+	}
+	return list, nil
+}
+
+// Format возвращает метки через пробел, каждую с # и своим цветом.
+func Format(list []string) string {
+	parts := make([]string, len(list))
+	for i, tag := range list {
+		parts[i] = Paint(tag)
+	}
+	return strings.Join(parts, " ")
+}
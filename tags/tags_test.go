@@ -0,0 +1,44 @@
+package tags
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	list, err := Parse("#Work, travel", "work", "личное")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 || list[0] != "work" || list[1] != "travel" || list[2] != "личное" {
+		t.Errorf("Expected normalized tags without duplicates, got %v", list)
+	}
+	if _, err := Parse("two words"); !errors.Is(err, ErrIsValidTag) {
+		t.Errorf("Expected ErrIsValidTag for a tag with a space, got %v", err)
+	}
+}
+
+func TestColors(t *testing.T) {
+	if err := SetColors("Work=blue, travel=cyan"); err != nil {
+		t.Fatal(err)
+	}
+	defer SetColors("")
+	if Color("work") != "blue" || Color("travel") != "cyan" {
+		t.Errorf("Expected assigned colors, got %s and %s", Color("work"), Color("travel"))
+	}
+	if Color("personal") != Color("personal") || colorCodes[Color("personal")] == "" {
+		t.Errorf("Expected a stable palette color, got %q", Color("personal"))
+	}
+	if _, err := ParseColors("work=pink"); err == nil {
+		t.Error("Expected an error for an unknown color")
+	}
+
+	SetEnabled(true)
+	if got := Paint("work"); got != "\x1b[34m#work\x1b[0m" {
+		t.Errorf("Expected a colored tag, got %q", got)
+	}
+	SetEnabled(false)
+	if got := Format([]string{"work", "travel"}); got != "#work #travel" {
+		t.Errorf("Expected plain tags without colors, got %q", got)
+	}
+}
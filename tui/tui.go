@@ -0,0 +1,388 @@
+package tui
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TsSol87/calendarApp/calendar"
+	"github.com/TsSol87/calendarApp/events"
+	"github.com/TsSol87/calendarApp/priority"
+	"github.com/TsSol87/calendarApp/views"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const (
+	modeMonth = "month"
+	modeWeek  = "week"
+)
+
+var priorities = []string{string(priority.PriorityHigh), string(priority.PriorityMedium), string(priority.PriorityLow)}
+
+type TUI struct {
+	calendar *calendar.Calendar
+	options  views.Options
+	selected time.Time
+	mode     string
+
+	app       *tview.Application
+	pages     *tview.Pages
+	grid      *tview.TextView
+	list      *tview.List
+	details   *tview.TextView
+	status    *tview.TextView
+	dayEvents []*events.Event
+	lastWidth int
+
+	mu       sync.Mutex
+	stopping bool
+	pending  int // обновления от Notify, ещё не выполненные главным циклом
+}
+
+func New(c *calendar.Calendar, opt views.Options) *TUI {
+	t := &TUI{
+		calendar: c,
+		options:  opt,
+		selected: views.StartOfDay(time.Now().In(opt.Location)),
+		mode:     modeMonth,
+		app:      tview.NewApplication(),
+	}
+
+	t.grid = tview.NewTextView().SetWrap(false)
+	t.grid.SetBorder(true)
+
+	t.list = tview.NewList().ShowSecondaryText(false)
+	t.list.SetBorder(true).SetTitle(" События ")
+	t.list.SetChangedFunc(func(index int, _ string, _ string, _ rune) {
+		t.showDetails(index)
+	})
+
+	t.details = tview.NewTextView().SetWrap(true)
+	t.details.SetBorder(true).SetTitle(" Подробности ")
+
+	t.status = tview.NewTextView()
+	t.setStatus("")
+
+	bottom := tview.NewFlex().
+		AddItem(t.list, 0, 1, false).
+		AddItem(t.details, 0, 1, false)
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(t.grid, 0, 1, true).
+		AddItem(bottom, 9, 0, false).
+		AddItem(t.status, 1, 0, false)
+
+	t.pages = tview.NewPages().AddPage("main", layout, true, true)
+	t.app.SetRoot(t.pages, true).SetInputCapture(t.handleKey)
+	t.app.SetBeforeDrawFunc(func(screen tcell.Screen) bool {
+		if width, _ := screen.Size(); width != t.lastWidth {
+			t.lastWidth = width
+			t.renderGrid()
+		}
+		return false
+	})
+	return t
+}
+
+func (t *TUI) Run() error {
+	t.refresh()
+	err := t.app.Run()
+	t.mu.Lock()
+	t.stopping = true
+	t.mu.Unlock()
+	return err
+}
+
+// Notify вызывается из горутины уведомлений. QueueUpdateDraw ждёт, пока главный цикл выполнит обновление,
+// поэтому после выхода уведомления отбрасываются, а сам выход откладывается до выполнения уже поставленных.
+func (t *TUI) Notify(msg string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopping {
+		return
+	}
+	t.pending++
+	go t.app.QueueUpdateDraw(func() {
+		t.setStatus("Напоминание: " + msg)
+		t.refresh()
+
+		t.mu.Lock()
+		t.pending--
+		stop := t.stopping && t.pending == 0
+		t.mu.Unlock()
+		if stop {
+			t.app.Stop()
+		}
+	})
+}
+
+// stop завершает главный цикл, как только выполнены все обновления от Notify: иначе их горутины ждали бы вечно.
+func (t *TUI) stop() {
+	t.mu.Lock()
+	t.stopping = true
+	pending := t.pending
+	t.mu.Unlock()
+	if pending == 0 {
+		t.app.Stop()
+	}
+}
+
+func (t *TUI) setStatus(msg string) {
+	hint := "←→↑↓ дни  PgUp/PgDn месяц  w неделя/месяц  t сегодня  Tab фокус  a добавить  e изменить  d удалить  q выход"
+	if msg != "" {
+		hint = msg + "  |  " + hint
+	}
+	t.status.SetText(hint)
+}
+
+func (t *TUI) handleKey(event *tcell.EventKey) *tcell.EventKey {
+	if name, _ := t.pages.GetFrontPage(); name != "main" {
+		return event
+	}
+
+	switch event.Key() {
+	case tcell.KeyTab:
+		if t.grid.HasFocus() {
+			t.app.SetFocus(t.list)
+		} else {
+			t.app.SetFocus(t.grid)
+		}
+		return nil
+	case tcell.KeyEscape, tcell.KeyCtrlC:
+		t.stop()
+		return nil
+	case tcell.KeyPgUp:
+		t.move(0, -1, 0)
+		return nil
+	case tcell.KeyPgDn:
+		t.move(0, 1, 0)
+		return nil
+	}
+
+	if t.grid.HasFocus() {
+		switch event.Key() {
+		case tcell.KeyLeft:
+			t.move(0, 0, -1)
+			return nil
+		case tcell.KeyRight:
+			t.move(0, 0, 1)
+			return nil
+		case tcell.KeyUp:
+			t.move(0, 0, -7)
+			return nil
+		case tcell.KeyDown:
+			t.move(0, 0, 7)
+			return nil
+		case tcell.KeyEnter:
+			t.app.SetFocus(t.list)
+			return nil
+		}
+	}
+
+	switch event.Rune() {
+	case 'q':
+		t.stop()
+	case 'w':
+		if t.mode == modeMonth {
+			t.mode = modeWeek
+		} else {
+			t.mode = modeMonth
+		}
+		t.refresh()
+	case 't':
+		t.selected = views.StartOfDay(time.Now().In(t.options.Location))
+		t.refresh()
+	case 'a':
+		t.showForm(nil)
+	case 'e':
+		if e := t.currentEvent(); e != nil {
+			t.showForm(e)
+		}
+	case 'd':
+		if e := t.currentEvent(); e != nil {
+			t.confirmDelete(e)
+		}
+	default:
+		return event
+	}
+	return nil
+}
+
+func (t *TUI) move(years, months, days int) {
+	t.selected = t.selected.AddDate(years, months, days)
+	t.refresh()
+}
+
+func (t *TUI) refresh() {
+	t.renderGrid()
+
+	from := views.StartOfDay(t.selected)
+	t.dayEvents = views.Between(t.allEvents(), from, from.AddDate(0, 0, 1))
+
+	current := t.list.GetCurrentItem()
+	t.list.Clear()
+	for _, e := range t.dayEvents {
+		label := fmt.Sprintf("%s %s %s", e.StartAt.In(t.options.Location).Format("15:04"), e.Priority.Marker(), e.Title)
+		t.list.AddItem(tview.Escape(label), "", 0, nil)
+	}
+	t.list.SetTitle(fmt.Sprintf(" События %s ", from.Format("02.01.2006")))
+	if current < len(t.dayEvents) {
+		t.list.SetCurrentItem(current)
+	}
+	t.showDetails(t.list.GetCurrentItem())
+}
+
+func (t *TUI) renderGrid() {
+	opt := t.options
+	opt.Selected = t.selected
+	if t.lastWidth > 0 {
+		opt.Width = t.lastWidth - 2
+	}
+
+	var buf bytes.Buffer
+	if t.mode == modeWeek {
+		views.Week(&buf, t.allEvents(), t.selected, opt)
+		t.grid.SetTitle(" Неделя ")
+	} else {
+		views.Month(&buf, t.allEvents(), t.selected, opt)
+		t.grid.SetTitle(" Месяц ")
+	}
+	t.grid.SetText(buf.String())
+}
+
+func (t *TUI) allEvents() []*events.Event {
+	list := make([]*events.Event, 0)
+	for _, e := range t.calendar.GetEvents() {
+		list = append(list, e)
+	}
+	return list
+}
+
+func (t *TUI) currentEvent() *events.Event {
+	i := t.list.GetCurrentItem()
+	if i < 0 || i >= len(t.dayEvents) {
+		return nil
+	}
+	return t.dayEvents[i]
+}
+
+func (t *TUI) showDetails(index int) {
+	if index < 0 || index >= len(t.dayEvents) {
+		t.details.SetText("Нет событий на выбранный день")
+		return
+	}
+	e := t.dayEvents[index]
+	date := e.StartAt.In(t.options.Location).Format(events.DateLayout())
+	if e.Zone != "" && e.Zone != t.options.Location.String() {
+		date += fmt.Sprintf(" (%s %s)", e.StartAt.In(e.Location()).Format("15:04"), e.Zone)
+	}
+	text := fmt.Sprintf("ID: %s\nСобытие: %s\nДата: %s\nПриоритет: %s\nНапоминание: %s",
+		e.ID, e.Title, date, e.Priority, e.Reminder.Format(t.options.Location))
+	if e.Place != "" {
+		text += "\nМесто: " + e.Place
+	}
+	if e.URL != "" {
+		text += "\nСсылка: " + e.URL
+	}
+	if len(e.Attendees) > 0 {
+		text += "\nУчастники: " + strings.Join(e.Attendees, ", ")
+	}
+	if len(e.Tags) > 0 {
+		text += "\nМетки: #" + strings.Join(e.Tags, " #")
+	}
+	if e.Description != "" {
+		text += "\n\n" + e.Description
+	}
+	t.details.SetText(text)
+}
+
+func (t *TUI) showForm(e *events.Event) {
+	title := ""
+	date := t.selected.Add(9 * time.Hour).Format(events.DateLayout())
+	priorityIndex := 1
+	formTitle := " Новое событие "
+	if e != nil {
+		title = e.Title
+		date = e.StartAt.In(t.options.Location).Format(events.DateLayout())
+		if e.Zone != "" && e.Zone != t.options.Location.String() {
+			date = e.StartAt.In(e.Location()).Format(events.DateLayout()) + " " + e.Zone
+		}
+		formTitle = " Изменить событие "
+		for i, p := range priorities {
+			if p == string(e.Priority) {
+				priorityIndex = i
+			}
+		}
+	}
+
+	form := tview.NewForm().
+		AddInputField("Название", title, 40, nil, nil).
+		AddInputField("Дата и время", date, 40, nil, nil).
+		AddDropDown("Приоритет", priorities, priorityIndex, nil)
+	form.SetBorder(true).SetTitle(formTitle)
+
+	closeForm := func() {
+		t.pages.RemovePage("form")
+		t.app.SetFocus(t.list)
+	}
+	form.AddButton("Сохранить", func() {
+		newTitle := form.GetFormItemByLabel("Название").(*tview.InputField).GetText()
+		newDate := form.GetFormItemByLabel("Дата и время").(*tview.InputField).GetText()
+		_, newPriority := form.GetFormItemByLabel("Приоритет").(*tview.DropDown).GetCurrentOption()
+
+		var err error
+		if e == nil {
+			_, err = t.calendar.AddEvent(newTitle, newDate, newPriority)
+		} else {
+			err = t.calendar.EditEvent(e.ID, newTitle, newDate, newPriority)
+		}
+		if err != nil {
+			t.setStatus("Ошибка: " + err.Error())
+			return
+		}
+		if at, err := events.TimeParse(newDate); err == nil {
+			t.selected = views.StartOfDay(at.In(t.options.Location))
+		}
+		closeForm()
+		t.setStatus("Сохранено")
+		t.refresh()
+	})
+	form.AddButton("Отмена", closeForm)
+	form.SetCancelFunc(closeForm)
+
+	t.pages.AddPage("form", centered(form, 66, 11), true, true)
+	t.app.SetFocus(form)
+}
+
+func (t *TUI) confirmDelete(e *events.Event) {
+	modal := tview.NewModal().
+		SetText(fmt.Sprintf("Удалить событие %q?", e.Title)).
+		AddButtons([]string{"Удалить", "Отмена"}).
+		SetDoneFunc(func(_ int, label string) {
+			t.pages.RemovePage("confirm")
+			t.app.SetFocus(t.list)
+			if label != "Удалить" {
+				return
+			}
+			if err := t.calendar.DeleteEvent(e.ID); err != nil {
+				t.setStatus("Ошибка: " + err.Error())
+				return
+			}
+			t.setStatus("Событие удалено")
+			t.refresh()
+		})
+	t.pages.AddPage("confirm", modal, true, true)
+	t.app.SetFocus(modal)
+}
+
+func centered(p tview.Primitive, width, height int) tview.Primitive {
+	return tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(p, height, 1, true).
+			AddItem(nil, 0, 1, false), width, 1, true).
+		AddItem(nil, 0, 1, false)
+}
@@ -0,0 +1,90 @@
+package tui
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TsSol87/calendarApp/calendar"
+	"github.com/TsSol87/calendarApp/events"
+	"github.com/TsSol87/calendarApp/storage"
+	"github.com/TsSol87/calendarApp/views"
+	"github.com/gdamore/tcell/v2"
+)
+
+func newTestTUI(t *testing.T) *TUI {
+	t.Helper()
+	c := calendar.NewCalendar(storage.NewRepository(storage.NewJsonStorage(filepath.Join(t.TempDir(), "data.json"))))
+	for _, e := range []struct{ title, date, priority string }{
+		{"Late", "2030-01-01 18:00", "low"},
+		{"Early", "2030-01-01 09:00", "high"},
+		{"Next day", "2030-01-02 10:00", "medium"},
+	} {
+		if _, err := c.AddEvent(e.title, e.date, e.priority); err != nil {
+			t.Fatal(err)
+		}
+	}
+	loc, err := time.LoadLocation(events.TimeZone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tui := New(c, views.Options{Width: 80, FirstWeekday: time.Monday, Location: loc})
+	tui.selected = time.Date(2030, 1, 1, 0, 0, 0, 0, loc)
+	return tui
+}
+
+func key(r rune) *tcell.EventKey {
+	return tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone)
+}
+
+func TestTUI_RefreshAndSelection(t *testing.T) {
+	tui := newTestTUI(t)
+	tui.refresh()
+
+	if len(tui.dayEvents) != 2 || tui.dayEvents[0].Title != "Early" || tui.dayEvents[1].Title != "Late" {
+		t.Fatalf("Expected the day's events in time order, got %v", tui.dayEvents)
+	}
+	if tui.list.GetItemCount() != 2 || tui.list.GetTitle() != " События 01.01.2030 " {
+		t.Errorf("Expected two items titled with the day, got %d %q", tui.list.GetItemCount(), tui.list.GetTitle())
+	}
+	if !strings.Contains(tui.details.GetText(false), "Событие: Early") {
+		t.Errorf("Expected details of the first event, got %q", tui.details.GetText(false))
+	}
+
+	tui.list.SetCurrentItem(1)
+	if e := tui.currentEvent(); e == nil || e.Title != "Late" {
+		t.Errorf("Expected the selected event to follow the list, got %v", e)
+	}
+	// После обновления выбор в списке сохраняется, пока такой пункт есть.
+	tui.refresh()
+	if e := tui.currentEvent(); e == nil || e.Title != "Late" {
+		t.Errorf("Expected refresh to keep the selection, got %v", e)
+	}
+
+	tui.move(0, 0, 1)
+	if len(tui.dayEvents) != 1 || tui.currentEvent().Title != "Next day" {
+		t.Errorf("Expected the next day's event, got %v", tui.dayEvents)
+	}
+	tui.move(0, 0, 1)
+	if tui.currentEvent() != nil || tui.details.GetText(false) != "Нет событий на выбранный день" {
+		t.Errorf("Expected an empty day, got %q", tui.details.GetText(false))
+	}
+
+	if !strings.Contains(tui.grid.GetTitle(), "Месяц") {
+		t.Errorf("Expected the month grid, got %q", tui.grid.GetTitle())
+	}
+	tui.handleKey(key('w'))
+	if tui.mode != modeWeek || !strings.Contains(tui.grid.GetTitle(), "Неделя") {
+		t.Errorf("Expected w to switch to the week grid, got %s %q", tui.mode, tui.grid.GetTitle())
+	}
+}
+
+func TestTUI_NotifyAfterStop(t *testing.T) {
+	tui := newTestTUI(t)
+	tui.handleKey(key('q'))
+	tui.Notify("Meeting")
+	if tui.pending != 0 {
+		t.Errorf("Expected notifications after exit to be dropped, got %d pending", tui.pending)
+	}
+}
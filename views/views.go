@@ -0,0 +1,294 @@
+package views
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TsSol87/calendarApp/events"
+	"github.com/TsSol87/calendarApp/tags"
+	"github.com/mattn/go-runewidth"
+	"golang.org/x/term"
+)
+
+const DefaultWidth = 80
+
+type Options struct {
+	Width        int
+	FirstWeekday time.Weekday
+	Location     *time.Location
+	Selected     time.Time
+	// Tags оставляет только события хотя бы с одной из меток.
+	Tags []string
+}
+
+var weekdayNames = [...]string{"Вс", "Пн", "Вт", "Ср", "Чт", "Пт", "Сб"}
+var weekdayFullNames = [...]string{"Воскресенье", "Понедельник", "Вторник", "Среда", "Четверг", "Пятница", "Суббота"}
+var monthNames = [...]string{"Январь", "Февраль", "Март", "Апрель", "Май", "Июнь", "Июль", "Август", "Сентябрь", "Октябрь", "Ноябрь", "Декабрь"}
+
+func TerminalWidth() int {
+	if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
+		return w
+	}
+	if w, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && w > 0 {
+		return w
+	}
+	return DefaultWidth
+}
+
+func ParseWeekday(s string) (time.Weekday, error) {
+	switch strings.ToLower(s) {
+	case "mon", "monday", "пн", "понедельник":
+		return time.Monday, nil
+	case "sun", "sunday", "вс", "воскресенье":
+		return time.Sunday, nil
+	case "sat", "saturday", "сб", "суббота":
+		return time.Saturday, nil
+	}
+	return time.Monday, fmt.Errorf("unsupported first weekday %q", s)
+}
+
+func (o Options) normalize() Options {
+	if o.Width <= 0 {
+		o.Width = DefaultWidth
+	}
+	if o.Location == nil {
+		o.Location = time.Local
+	}
+	return o
+}
+
+func StartOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+func StartOfWeek(t time.Time, first time.Weekday) time.Time {
+	day := StartOfDay(t)
+	offset := (int(day.Weekday()) - int(first) + 7) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+func Between(list []*events.Event, from, to time.Time) []*events.Event {
+	var result []*events.Event
+	for _, e := range list {
+		if !e.StartAt.Before(from) && e.StartAt.Before(to) {
+			result = append(result, e)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StartAt.Before(result[j].StartAt)
+	})
+	return result
+}
+
+func fit(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	return runewidth.FillRight(runewidth.Truncate(s, width, "…"), width)
+}
+
+func line(left, middle, right string, cells, width int) string {
+	segment := strings.Repeat("─", width)
+	parts := make([]string, cells)
+	for i := range parts {
+		parts[i] = segment
+	}
+	return left + strings.Join(parts, middle) + right
+}
+
+func eventLabel(e *events.Event, loc *time.Location) string {
+	return fmt.Sprintf("%s%s %s", e.StartAt.In(loc).Format("15:04"), e.Priority.Marker(), e.Title)
+}
+
+// dayLabel добавляет к событию его цветные метки, если они помещаются в строку целиком.
+func dayLabel(e *events.Event, loc *time.Location, width int) string {
+	label := eventLabel(e, loc)
+	if len(e.Tags) == 0 {
+		return runewidth.Truncate(label, width, "…")
+	}
+	plain := label + " #" + strings.Join(e.Tags, " #")
+	if runewidth.StringWidth(plain) > width {
+		return runewidth.Truncate(plain, width, "…")
+	}
+	return label + " " + tags.Format(e.Tags)
+}
+
+func Day(w io.Writer, list []*events.Event, day time.Time, opt Options) {
+	opt = opt.normalize()
+	list = events.FilterTags(list, opt.Tags)
+	from := StartOfDay(day.In(opt.Location))
+	dayEvents := Between(list, from, from.AddDate(0, 0, 1))
+
+	_, week := from.ISOWeek()
+	fmt.Fprintf(w, "%s, %s (неделя %d)\n", weekdayFullNames[from.Weekday()], from.Format("02.01.2006"), week)
+
+	byHour := make(map[int][]*events.Event)
+	for _, e := range dayEvents {
+		h := e.StartAt.In(opt.Location).Hour()
+		byHour[h] = append(byHour[h], e)
+	}
+
+	textWidth := opt.Width - 8
+	for h := 0; h < 24; h++ {
+		hourEvents := byHour[h]
+		if len(hourEvents) == 0 {
+			fmt.Fprintf(w, "%02d:00 │\n", h)
+			continue
+		}
+		for i, e := range hourEvents {
+			prefix := "      │ "
+			if i == 0 {
+				prefix = fmt.Sprintf("%02d:00 │ ", h)
+			}
+			fmt.Fprintln(w, prefix+dayLabel(e, opt.Location, textWidth))
+		}
+	}
+}
+
+// dayIndex возвращает номер дня недели, начатой в from, для момента t или -1, если t вне недели.
+// Дни сравниваются по календарю: при переходе на летнее время в сутках не 24 часа.
+func dayIndex(from, t time.Time) int {
+	day := StartOfDay(t)
+	for i := 0; i < 7; i++ {
+		if from.AddDate(0, 0, i).Equal(day) {
+			return i
+		}
+	}
+	return -1
+}
+
+func Week(w io.Writer, list []*events.Event, day time.Time, opt Options) {
+	opt = opt.normalize()
+	list = events.FilterTags(list, opt.Tags)
+	from := StartOfWeek(day.In(opt.Location), opt.FirstWeekday)
+	to := from.AddDate(0, 0, 7)
+
+	columns := make([][]*events.Event, 7)
+	rows := 0
+	for _, e := range Between(list, from, to) {
+		i := dayIndex(from, e.StartAt.In(opt.Location))
+		if i < 0 {
+			continue
+		}
+		columns[i] = append(columns[i], e)
+		if len(columns[i]) > rows {
+			rows = len(columns[i])
+		}
+	}
+
+	cellWidth := (opt.Width-1)/7 - 1
+	if cellWidth < 5 {
+		cellWidth = 5
+	}
+
+	year, week := from.ISOWeek()
+	fmt.Fprintf(w, "Неделя %d, %d: %s – %s\n", week, year, from.Format("02.01"), to.AddDate(0, 0, -1).Format("02.01"))
+	fmt.Fprintln(w, line("┌", "┬", "┐", 7, cellWidth))
+
+	var header strings.Builder
+	header.WriteString("│")
+	for i := 0; i < 7; i++ {
+		d := from.AddDate(0, 0, i)
+		label := weekdayNames[d.Weekday()] + " " + d.Format("02.01")
+		if !opt.Selected.IsZero() && d.Equal(StartOfDay(opt.Selected.In(opt.Location))) {
+			label = "[" + label + "]"
+		}
+		header.WriteString(fit(label, cellWidth) + "│")
+	}
+	fmt.Fprintln(w, header.String())
+	fmt.Fprintln(w, line("├", "┼", "┤", 7, cellWidth))
+
+	if rows == 0 {
+		rows = 1
+	}
+	for r := 0; r < rows; r++ {
+		var row strings.Builder
+		row.WriteString("│")
+		for i := 0; i < 7; i++ {
+			text := ""
+			if r < len(columns[i]) {
+				text = eventLabel(columns[i][r], opt.Location)
+			}
+			row.WriteString(fit(text, cellWidth) + "│")
+		}
+		fmt.Fprintln(w, row.String())
+	}
+	fmt.Fprintln(w, line("└", "┴", "┘", 7, cellWidth))
+}
+
+func Month(w io.Writer, list []*events.Event, month time.Time, opt Options) {
+	opt = opt.normalize()
+	list = events.FilterTags(list, opt.Tags)
+	month = month.In(opt.Location)
+	first := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, opt.Location)
+	next := first.AddDate(0, 1, 0)
+	today := StartOfDay(time.Now().In(opt.Location))
+
+	counts := make(map[int][]*events.Event)
+	for _, e := range Between(list, first, next) {
+		d := e.StartAt.In(opt.Location).Day()
+		counts[d] = append(counts[d], e)
+	}
+
+	const weekColumn = 3
+	cellWidth := (opt.Width-weekColumn-2)/7 - 1
+	if cellWidth < 4 {
+		cellWidth = 4
+	}
+
+	fmt.Fprintf(w, "%s %d\n", monthNames[first.Month()-1], first.Year())
+	fmt.Fprintln(w, strings.Repeat(" ", weekColumn)+line("┌", "┬", "┐", 7, cellWidth))
+
+	var header strings.Builder
+	header.WriteString(fit("Нд", weekColumn) + "│")
+	for i := 0; i < 7; i++ {
+		header.WriteString(fit(weekdayNames[(int(opt.FirstWeekday)+i)%7], cellWidth) + "│")
+	}
+	fmt.Fprintln(w, header.String())
+
+	for start := StartOfWeek(first, opt.FirstWeekday); start.Before(next); start = start.AddDate(0, 0, 7) {
+		fmt.Fprintln(w, strings.Repeat(" ", weekColumn)+line("├", "┼", "┤", 7, cellWidth))
+
+		var days, marks strings.Builder
+		_, week := start.AddDate(0, 0, (int(time.Thursday)-int(start.Weekday())+7)%7).ISOWeek()
+		days.WriteString(fit(strconv.Itoa(week), weekColumn) + "│")
+		marks.WriteString(strings.Repeat(" ", weekColumn) + "│")
+		for i := 0; i < 7; i++ {
+			d := start.AddDate(0, 0, i)
+			if d.Month() != first.Month() {
+				days.WriteString(fit("", cellWidth) + "│")
+				marks.WriteString(fit("", cellWidth) + "│")
+				continue
+			}
+			label := fmt.Sprintf("%2d", d.Day())
+			if d.Equal(today) {
+				label = fmt.Sprintf(">%d", d.Day())
+			}
+			if !opt.Selected.IsZero() && d.Equal(StartOfDay(opt.Selected.In(opt.Location))) {
+				label = fmt.Sprintf("[%s]", strings.TrimSpace(label))
+			}
+			days.WriteString(fit(label, cellWidth) + "│")
+
+			dayEvents := counts[d.Day()]
+			text := ""
+			if len(dayEvents) > 0 {
+				var markers strings.Builder
+				for _, e := range dayEvents {
+					markers.WriteString(e.Priority.Marker())
+				}
+				text = fmt.Sprintf("%d %s", len(dayEvents), markers.String())
+			}
+			marks.WriteString(fit(text, cellWidth) + "│")
+		}
+		fmt.Fprintln(w, days.String())
+		fmt.Fprintln(w, marks.String())
+	}
+	fmt.Fprintln(w, strings.Repeat(" ", weekColumn)+line("└", "┴", "┘", 7, cellWidth))
+	fmt.Fprintln(w, "Приоритет: ! высокий  * средний  · низкий")
+}
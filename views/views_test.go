@@ -0,0 +1,159 @@
+package views
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TsSol87/calendarApp/events"
+	"github.com/TsSol87/calendarApp/priority"
+	"github.com/TsSol87/calendarApp/tags"
+)
+
+func testEvent(t *testing.T, title string, at time.Time, p string, labels ...string) *events.Event {
+	t.Helper()
+	return &events.Event{ID: title, Title: title, StartAt: at.UTC(), Zone: at.Location().String(), Priority: priority.Priority(p), Tags: labels}
+}
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+// cells разбивает строку таблицы на ячейки без рамки.
+func cells(line string) []string {
+	parts := strings.Split(line, "│")
+	var result []string
+	for _, p := range parts[1 : len(parts)-1] {
+		result = append(result, strings.TrimSpace(p))
+	}
+	return result
+}
+
+func TestWeek_Columns(t *testing.T) {
+	tags.SetEnabled(false)
+	berlin := mustLocation(t, "Europe/Berlin")
+	tests := []struct {
+		name   string
+		first  time.Weekday
+		day    time.Time
+		event  time.Time
+		header string
+		column int
+	}{
+		{"monday first", time.Monday, time.Date(2025, 3, 12, 0, 0, 0, 0, berlin), time.Date(2025, 3, 14, 10, 0, 0, 0, berlin), "Пн 10.03", 4},
+		{"sunday first", time.Sunday, time.Date(2025, 3, 12, 0, 0, 0, 0, berlin), time.Date(2025, 3, 14, 10, 0, 0, 0, berlin), "Вс 09.03", 5},
+		// 30 марта в Берлине переходят на летнее время: в этих сутках 23 часа.
+		{"after spring forward", time.Sunday, time.Date(2025, 3, 30, 0, 0, 0, 0, berlin), time.Date(2025, 3, 31, 10, 0, 0, 0, berlin), "Вс 30.03", 1},
+		{"end of week after spring forward", time.Monday, time.Date(2025, 3, 30, 0, 0, 0, 0, berlin), time.Date(2025, 3, 30, 23, 30, 0, 0, berlin), "Пн 24.03", 6},
+		{"after fall back", time.Sunday, time.Date(2025, 10, 26, 0, 0, 0, 0, berlin), time.Date(2025, 11, 1, 0, 30, 0, 0, berlin), "Вс 26.10", 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			list := []*events.Event{testEvent(t, "Meet", tt.event, "high")}
+			Week(&out, list, tt.day, Options{Width: 120, FirstWeekday: tt.first, Location: berlin})
+			lines := strings.Split(out.String(), "\n")
+			header := cells(lines[2])
+			if header[0] != tt.header {
+				t.Errorf("Expected the week to start with %q, got %q", tt.header, header[0])
+			}
+			row := cells(lines[4])
+			for i, cell := range row {
+				if (i == tt.column) != strings.Contains(cell, "Meet") {
+					t.Errorf("Expected the event in column %d, got row %q", tt.column, row)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestDay_HoursAndTags(t *testing.T) {
+	tags.SetEnabled(false)
+	berlin := mustLocation(t, "Europe/Berlin")
+	day := time.Date(2025, 3, 30, 0, 0, 0, 0, berlin)
+	list := []*events.Event{
+		testEvent(t, "Flight", time.Date(2025, 3, 30, 10, 0, 0, 0, berlin), "high", "travel"),
+		testEvent(t, "Standup", time.Date(2025, 3, 30, 9, 0, 0, 0, berlin), "high", "work"),
+		testEvent(t, "Tomorrow", time.Date(2025, 3, 31, 10, 0, 0, 0, berlin), "high"),
+	}
+	tests := []struct {
+		name    string
+		tags    []string
+		want    []string
+		missing []string
+	}{
+		{"all", nil, []string{"09:00 │ 09:00! Standup #work", "10:00 │ 10:00! Flight #travel"}, []string{"Tomorrow"}},
+		{"by tag", []string{"travel"}, []string{"10:00 │ 10:00! Flight #travel"}, []string{"Standup", "Tomorrow"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			Day(&out, list, day, Options{Width: 80, Location: berlin, Tags: tt.tags})
+			got := out.String()
+			if !strings.HasPrefix(got, "Воскресенье, 30.03.2025") {
+				t.Errorf("Unexpected header in %q", got)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want+"\n") {
+					t.Errorf("Expected line %q in\n%s", want, got)
+				}
+			}
+			for _, title := range tt.missing {
+				if strings.Contains(got, title) {
+					t.Errorf("Expected %s to be filtered out", title)
+				}
+			}
+		})
+	}
+}
+
+func TestMonth_FirstWeekdayAndCounts(t *testing.T) {
+	berlin := mustLocation(t, "Europe/Berlin")
+	list := []*events.Event{
+		testEvent(t, "A", time.Date(2025, 3, 30, 1, 30, 0, 0, berlin), "high"),
+		testEvent(t, "B", time.Date(2025, 3, 30, 23, 30, 0, 0, berlin), "low"),
+		testEvent(t, "C", time.Date(2025, 4, 1, 0, 30, 0, 0, berlin), "high"),
+	}
+	tests := []struct {
+		first  time.Weekday
+		header string
+		column int // ячейка 30 марта
+	}{
+		{time.Monday, "Пн", 6},
+		{time.Sunday, "Вс", 0},
+		{time.Saturday, "Сб", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			var out bytes.Buffer
+			Month(&out, list, time.Date(2025, 3, 15, 0, 0, 0, 0, berlin), Options{Width: 80, FirstWeekday: tt.first, Location: berlin})
+			lines := strings.Split(out.String(), "\n")
+			if header := cells(lines[2]); header[0] != tt.header {
+				t.Errorf("Expected the week to start with %s, got %v", tt.header, header)
+			}
+			found := false
+			for i, line := range lines {
+				if !strings.Contains(line, "│30") {
+					continue
+				}
+				found = true
+				if days := cells(line); days[tt.column] != "30" {
+					t.Errorf("Expected the 30th in column %d, got %q", tt.column, days)
+				}
+				if marks := cells(lines[i+1]); marks[tt.column] != "2 !·" || marks[(tt.column+1)%7] != "" {
+					t.Errorf("Expected only two events on the 30th, got %q", marks)
+				}
+			}
+			if !found {
+				t.Errorf("Expected the 30th in the calendar:\n%s", out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package views
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type WorkHours struct {
+	Start int
+	End   int
+}
+
+func (h WorkHours) contains(t time.Time) bool {
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return false
+	}
+	return t.Hour() >= h.Start && t.Hour() < h.End
+}
+
+func zoneName(loc *time.Location) string {
+	name := loc.String()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.ReplaceAll(name, "_", " ")
+}
+
+func Clock(w io.Writer, at time.Time, zones []*time.Location) {
+	for _, loc := range zones {
+		local := at.In(loc)
+		fmt.Fprintf(w, "  %-20s %s %s (UTC%s)\n", loc.String(), weekdayNames[local.Weekday()], local.Format("02.01.2006 15:04"), local.Format("-07:00"))
+	}
+}
+
+// Planner показывает часы выбранного дня в зоне просмотра и отмечает, где рабочее время совпадает во всех зонах.
+func Planner(w io.Writer, day time.Time, zones []*time.Location, hours WorkHours, opt Options) {
+	opt = opt.normalize()
+	from := StartOfDay(day.In(opt.Location))
+	all := append([]*time.Location{opt.Location}, zones...)
+
+	cellWidth := (opt.Width-4)/len(all) - 3
+	if cellWidth < 8 {
+		cellWidth = 8
+	}
+
+	fmt.Fprintf(w, "%s, %s — рабочие часы %02d:00–%02d:00\n", weekdayFullNames[from.Weekday()], from.Format("02.01.2006"), hours.Start, hours.End)
+	var header strings.Builder
+	header.WriteString("  ")
+	for _, loc := range all {
+		header.WriteString("│ " + fit(zoneName(loc), cellWidth) + " ")
+	}
+	fmt.Fprintln(w, header.String()+"│")
+
+	// Строки строятся по часам на циферблате: в день перехода на летнее время часа нет, а при обратном
+	// переходе он повторяется, поэтому прибавлять к началу дня по часу нельзя.
+	type hour struct {
+		row int
+		at  time.Time
+	}
+	var overlap []hour
+	y, m, d := from.Date()
+	row := 0
+	for h := 0; h < 24; h++ {
+		at := time.Date(y, m, d, h, 0, 0, 0, opt.Location)
+		if at.Hour() != h {
+			continue
+		}
+		row++
+		common := true
+		var line strings.Builder
+		for _, loc := range all {
+			local := at.In(loc)
+			mark := " "
+			if hours.contains(local) {
+				mark = "■"
+			} else {
+				common = false
+			}
+			line.WriteString("│ " + fit(fmt.Sprintf("%s %s %s", local.Format("15:04"), mark, weekdayNames[local.Weekday()]), cellWidth) + " ")
+		}
+		prefix := "  "
+		if common {
+			prefix = "» "
+			overlap = append(overlap, hour{row, at})
+		}
+		fmt.Fprintln(w, prefix+line.String()+"│")
+	}
+
+	if len(overlap) == 0 {
+		fmt.Fprintln(w, "Общих рабочих часов нет")
+		return
+	}
+	var ranges []string
+	start := overlap[0]
+	for i := 1; i <= len(overlap); i++ {
+		if i == len(overlap) || overlap[i].row != overlap[i-1].row+1 {
+			ranges = append(ranges, start.at.Format("15:04")+"–"+overlap[i-1].at.Add(time.Hour).Format("15:04"))
+			if i < len(overlap) {
+				start = overlap[i]
+			}
+		}
+	}
+	fmt.Fprintf(w, "Общие рабочие часы (%s): %s\n", opt.Location, strings.Join(ranges, ", "))
+}
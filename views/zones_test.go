@@ -0,0 +1,78 @@
+package views
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	at := time.Date(2025, 3, 10, 14, 0, 0, 0, time.UTC)
+	var out bytes.Buffer
+	Clock(&out, at, []*time.Location{mustLocation(t, "Asia/Tokyo"), mustLocation(t, "America/New_York")})
+	want := "  Asia/Tokyo           Пн 10.03.2025 23:00 (UTC+09:00)\n" +
+		"  America/New_York     Пн 10.03.2025 10:00 (UTC-04:00)\n"
+	if out.String() != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, out.String())
+	}
+}
+
+// plannerRows возвращает время в первой колонке каждой строки часов и строку с общими часами.
+func plannerRows(out string) ([]string, string) {
+	var rows []string
+	summary := ""
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "Общ") {
+			summary = line
+		}
+		if strings.Count(line, "│") < 2 {
+			continue
+		}
+		c := cells(line)
+		if len(c) == 0 || !strings.Contains(c[0], ":") {
+			continue
+		}
+		rows = append(rows, strings.Fields(c[0])[0])
+	}
+	return rows, summary
+}
+
+func TestPlanner(t *testing.T) {
+	berlin := mustLocation(t, "Europe/Berlin")
+	newYork := mustLocation(t, "America/New_York")
+	hours := WorkHours{Start: 9, End: 18}
+	tests := []struct {
+		name    string
+		day     time.Time
+		rows    int
+		last    string
+		summary string
+	}{
+		{"ordinary day", time.Date(2025, 1, 15, 0, 0, 0, 0, berlin), 24, "23:00", "Общие рабочие часы (Europe/Berlin): 15:00–18:00"},
+		// В Нью-Йорке уже летнее время, в Берлине — ещё нет: разница пять часов.
+		{"new york on summer time", time.Date(2025, 3, 12, 0, 0, 0, 0, berlin), 24, "23:00", "Общие рабочие часы (Europe/Berlin): 14:00–18:00"},
+		// 30 марта 2025 — воскресенье и переход на летнее время в Берлине: 02:00 нет.
+		{"spring forward", time.Date(2025, 3, 30, 0, 0, 0, 0, berlin), 23, "23:00", "Общих рабочих часов нет"},
+		{"day after spring forward", time.Date(2025, 3, 31, 0, 0, 0, 0, berlin), 24, "23:00", "Общие рабочие часы (Europe/Berlin): 15:00–18:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			Planner(&out, tt.day, []*time.Location{newYork}, hours, Options{Width: 80, Location: berlin})
+			rows, summary := plannerRows(out.String())
+			if len(rows) != tt.rows || rows[len(rows)-1] != tt.last {
+				t.Errorf("Expected %d hours ending with %s, got %v", tt.rows, tt.last, rows)
+			}
+			for i := 1; i < len(rows); i++ {
+				if rows[i] <= rows[i-1] {
+					t.Errorf("Expected hours to increase, got %v", rows)
+					break
+				}
+			}
+			if summary != tt.summary {
+				t.Errorf("Expected %q, got %q", tt.summary, summary)
+			}
+		})
+	}
+}